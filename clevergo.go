@@ -55,6 +55,29 @@
 //   /blog/go/                           no match
 //   /blog/go/request-routers/comments   no match
 //
+// A trailing named parameter can be marked as optional by the '?' suffix,
+// the route matches the path with and without the parameter:
+//  Path: /files/:name?
+//
+//  Requests:
+//   /files                              match: name=""
+//   /files/report                       match: name="report"
+//   /files/                             no match, but the router would redirect
+//
+// A segment may contain multiple named parameters separated by delimiters,
+// the leading parameters take the longest possible values:
+//  Path: /files/:name.:ext
+//
+//  Requests:
+//   /files/report.pdf                   match: name="report", ext="pdf"
+//   /files/my.report.pdf                match: name="my.report", ext="pdf"
+//   /files/report                       no match
+//
+// The segment takes the place of its first parameter in the route tree, so
+// that it conflicts with a route which has a plain parameter or another
+// compound segment in the same position, such as "/files/:name" or
+// "/files/:base-:size", and the paths without the delimiters are not found.
+//
 // Catch-all parameters match anything until the path end, including the
 // directory index (the '/' before the catch-all). Since they match anything
 // until the end, catch-all parameters must always be the final path element.
@@ -66,6 +89,30 @@
 //   /files/templates/article.html       match: filepath="/templates/article.html"
 //   /files                              no match, but the router would redirect
 //
+// A catch-all parameter at the root, such as "/*path", has the lowest priority,
+// it matches any request which is not matched by other routes.
+//
+// The ':' and '*' characters escaped by a backslash are matched literally,
+// note that the backslash must be escaped in interpreted string literals:
+//  Path: `/ratio/16\:9`
+//
+//  Requests:
+//   /ratio/16:9                         match
+//   /ratio/4:3                          no match
+//
+// Routes have no priority or ordering, a request path is matched by at most
+// one route of the method, since routes that could match the same path, such
+// as "/users/new" and "/users/:id", conflict and cause a panic on registration,
+// regardless of the registration order. The only fallbacks are tried in order:
+//  1. the route of the request method
+//  2. the root catch-all route of the request method
+//  3. the GET route for HEAD requests, if Router.HandleHEAD is enabled
+//  4. the route of the wildcard method MethodAny
+//
+// A handler may decline a request by Pass, in which case the fallbacks of its
+// route registered by RouteFallback, the root catch-all route and the next
+// steps above are tried, before falling back to the NotFound handler.
+//
 // The value of parameters is saved as a slice of the Param struct, consisting
 // each of a key and a value. The slice is passed to the Handle func as a third
 // parameter.
@@ -80,6 +127,7 @@
 package clevergo
 
 import (
+	"context"
 	"net"
 	"net/http"
 )
@@ -103,7 +151,8 @@ func New(addr string) *Application {
 	}
 }
 
-// Use registers middlewares.
+// Use registers middlewares which wrap the whole router, unlike Router.Use,
+// they are also invoked for unmatched requests.
 func (app *Application) Use(middlewares ...Middleware) {
 	app.middlewares = append(app.middlewares, middlewares...)
 }
@@ -112,6 +161,13 @@ func (app *Application) prepare() {
 	app.Server.Handler = Chain(app.Router, app.middlewares...)
 }
 
+// Shutdown gracefully shuts down the server, see http.Server.Shutdown.
+// It resolves the ambiguity between Router.Shutdown and http.Server.Shutdown,
+// the latter already waits for the active requests.
+func (app *Application) Shutdown(ctx context.Context) error {
+	return app.Server.Shutdown(ctx)
+}
+
 // ListenAndServe overrides http.Server.ListenAndServe with extra preparations.
 func (app *Application) ListenAndServe() error {
 	app.prepare()
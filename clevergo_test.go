@@ -15,6 +15,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 var (
@@ -156,6 +157,29 @@ func TestApplicationListenAndServe(t *testing.T) {
 	}
 }
 
+func TestApplicationShutdown(t *testing.T) {
+	app := New("localhost:12346")
+	served := make(chan error)
+	go func() {
+		served <- app.ListenAndServe()
+	}()
+
+	// waits until the server is listening.
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", app.Addr); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := <-served; err != http.ErrServerClosed {
+		t.Errorf("expected error %v, got %v", http.ErrServerClosed, err)
+	}
+}
+
 func TestApplicationListenAndServeTLS(t *testing.T) {
 	addr := "localhost:12345"
 	body := "ListenAndServeTLS"
@@ -0,0 +1,121 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const (
+	ctxKey contextKey = iota
+	requestIDKey
+)
+
+// Context holds the request-scoped data of the router, such as params, matched
+// route and custom values, it is attached onto the context of matched requests,
+// so that all of them can be retrieved by a single context lookup.
+type Context struct {
+	// Params of the request.
+	Params Params
+
+	// Matched route of the request, it is only set if
+	// Router.SaveMatchedRoute is turn on.
+	Route *Route
+
+	// Allowed methods of the request path, it is only set for the
+	// Router.GlobalOPTIONS and Router.MethodNotAllowed handlers.
+	AllowedMethods []string
+
+	values map[string]interface{}
+
+	// origin is the context attached by the router, the copies made by
+	// withContext share its pass signal.
+	origin *Context
+	passed bool
+}
+
+// GetContext returns the router context of the request, nil is returned
+// if the request was not dispatched by the router.
+func GetContext(req *http.Request) *Context {
+	c, _ := req.Context().Value(ctxKey).(*Context)
+	return c
+}
+
+// GetAllowedMethods returns the allowed methods of the request path, it is
+// intended for the Router.GlobalOPTIONS and Router.MethodNotAllowed handlers,
+// nil is returned for other requests.
+func GetAllowedMethods(req *http.Request) []string {
+	if c := GetContext(req); c != nil {
+		return c.AllowedMethods
+	}
+	return nil
+}
+
+// withContext returns a shallow copy of the request with a copy of its
+// router context modified by the given function.
+func withContext(req *http.Request, f func(c *Context)) *http.Request {
+	c := &Context{}
+	if old := GetContext(req); old != nil {
+		*c = *old
+		c.origin = old.root()
+	}
+	f(c)
+	return req.WithContext(context.WithValue(req.Context(), ctxKey, c))
+}
+
+// Value returns the value associated with the given key, nil is returned
+// if no value is associated with the key, or if c is nil.
+func (c *Context) Value(key string) interface{} {
+	if c == nil {
+		return nil
+	}
+	return c.values[key]
+}
+
+// SetValue associates the value with the given key, it is not safe for
+// concurrent use. It does nothing if c is nil, that is, the request has no
+// router context, see GetContext.
+func (c *Context) SetValue(key string, value interface{}) {
+	if c == nil {
+		return
+	}
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = value
+}
+
+func (c *Context) root() *Context {
+	if c.origin != nil {
+		return c.origin
+	}
+	return c
+}
+
+// Pass signals the router that the handler declines the request, so that the
+// router continues trying the fallbacks of the route, see RouteFallback, and
+// then the other routes which match the request, such as the root catch-all
+// route and the route of MethodAny, the NotFound handler is called if none of
+// them accepts the request. The handler must not write the response before
+// passing. It does nothing if the request was not dispatched by the router,
+// or if the route has no params, middlewares and nothing to fall back to
+// while Router.SaveMatchedRoute is off, since the router context is not
+// attached for such static routes to avoid the allocations.
+func Pass(req *http.Request) {
+	if c := GetContext(req); c != nil {
+		c.root().passed = true
+	}
+}
+
+// takePassed reports whether the request was passed, and resets the signal.
+func (c *Context) takePassed() bool {
+	c = c.root()
+	passed := c.passed
+	c.passed = false
+	return passed
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetContext(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if c := GetContext(req); c != nil {
+		t.Errorf("expected nil context, got %v", c)
+	}
+
+	router := NewRouter()
+	router.SaveMatchedRoute = true
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			GetContext(r).SetValue("user", "gopher")
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.HandleFunc(http.MethodGet, "/posts/:id", func(w http.ResponseWriter, r *http.Request) {
+		c := GetContext(r)
+		if c.Route == nil || c.Route.path != "/posts/:id" {
+			t.Errorf("unexpected route: %v", c.Route)
+		}
+		fmt.Fprintf(w, "%s %s %v", c.Params.Get("id"), c.Value("user"), c.Value("nope"))
+	})
+	router.Handle(http.MethodGet, "/", echoHandler("home"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+	if w.Body.String() != "1 gopher <nil>" {
+		t.Errorf("expected body %q, got %q", "1 gopher <nil>", w.Body)
+	}
+
+	// the context is attached even if there are no params.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Body.String() != "home" {
+		t.Errorf("expected body %q, got %q", "home", w.Body)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	ps := Params{Param{"name", "gopher"}}
+	route := newRoute("/users/:name", echoHandler(""))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req1 := WithParams(req, ps)
+	req2 := WithRoute(req1, route)
+	GetContext(req2).SetValue("foo", "bar")
+
+	if GetRoute(req1) != nil {
+		t.Error("the context of original request should not be modified")
+	}
+	if c := GetContext(req2); !reflect.DeepEqual(c.Params, ps) || c.Route != route || c.Value("foo") != "bar" {
+		t.Errorf("unexpected context: %+v", c)
+	}
+}
+
+func TestGetAllowedMethods(t *testing.T) {
+	var allowed []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		allowed = GetAllowedMethods(req)
+	})
+	router := NewRouter()
+	router.GlobalOPTIONS = handler
+	router.MethodNotAllowed = handler
+	router.Post("/path", func(http.ResponseWriter, *http.Request) {})
+	router.Delete("/path", func(http.ResponseWriter, *http.Request) {})
+
+	for _, method := range []string{http.MethodOptions, http.MethodGet} {
+		allowed = nil
+		req, _ := http.NewRequest(method, "/path", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		expected := []string{http.MethodDelete, http.MethodOptions, http.MethodPost}
+		if !reflect.DeepEqual(allowed, expected) {
+			t.Errorf("%s: expected allowed methods %v, got %v", method, expected, allowed)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/path", nil)
+	if allowed = GetAllowedMethods(req); allowed != nil {
+		t.Errorf("expected nil allowed methods, got %v", allowed)
+	}
+}
+
+func TestRouterStaticRouteNoContext(t *testing.T) {
+	router := NewRouter()
+	var c *Context
+	router.Get("/static", func(w http.ResponseWriter, req *http.Request) {
+		c = GetContext(req)
+	})
+	router.Get("/users/:id", func(w http.ResponseWriter, req *http.Request) {
+		c = GetContext(req)
+	})
+
+	w := new(mockResponseWriter)
+	req := httptest.NewRequest(http.MethodGet, "/static", nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		router.ServeHTTP(w, req)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations for static route, got %v", allocs)
+	}
+	if c != nil {
+		t.Errorf("expected no context for static route, got %v", c)
+	}
+
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if c == nil {
+		t.Error("expected context for route with params")
+	}
+
+	router.SaveMatchedRoute = true
+	router.ServeHTTP(w, req)
+	if c == nil || c.Route == nil {
+		t.Error("expected context with matched route")
+	}
+
+	router.SaveMatchedRoute = false
+	c = nil
+	router.Handle(MethodAny, "/static", echoHandler("any"))
+	router.ServeHTTP(w, req)
+	if c == nil {
+		t.Error("expected context for route which can fall back")
+	}
+}
+
+func TestContextValueStaticRoute(t *testing.T) {
+	router := NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			GetContext(req).SetValue("user", "bob")
+			next.ServeHTTP(w, req)
+		})
+	})
+	router.Get("/static", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, GetContext(req).Value("user"))
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static", nil))
+	if w.Body.String() != "bob" {
+		t.Errorf("expected body %q, got %q", "bob", w.Body)
+	}
+
+	var c *Context
+	c.SetValue("user", "bob")
+	if v := c.Value("user"); v != nil {
+		t.Errorf("expected nil value of nil context, got %v", v)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig contains the options of the CORS middleware.
+type CORSConfig struct {
+	// AllowedOrigins is a list of origins a cross-domain request can be
+	// executed from, "*" allows all origins.
+	AllowedOrigins []string
+
+	// AllowedMethods is a list of methods the client is allowed to use with
+	// cross-domain requests. If it is empty, the methods allowed by the
+	// router for the requested path are used, see GetAllowedMethods.
+	AllowedMethods []string
+
+	// AllowedHeaders is a list of non-simple headers the client is allowed to
+	// use with cross-domain requests. If it is empty, the headers requested
+	// by the preflight request are allowed.
+	AllowedHeaders []string
+
+	// AllowCredentials indicates whether the request can include user
+	// credentials like cookies or HTTP authentication.
+	AllowCredentials bool
+
+	// MaxAge indicates how long (in seconds) the results of a preflight
+	// request can be cached, zero means no Access-Control-Max-Age header.
+	MaxAge int
+}
+
+// CORS returns a middleware that handles Cross-Origin Resource Sharing.
+//
+// Preflight requests are answered by the middleware, the next handler is not
+// called, and actual requests get the Access-Control-Allow-Origin header.
+// Since the router only dispatches matched requests to its middlewares, the
+// preflight requests should be handled by Router.GlobalOPTIONS, which allows
+// the middleware to reflect the methods the router computes for the path:
+//  cors := clevergo.CORS(clevergo.CORSConfig{AllowedOrigins: []string{"https://example.com"}})
+//  router.Use(cors)
+//  router.GlobalOPTIONS = cors(nil)
+func CORS(config CORSConfig) Middleware {
+	c := &cors{config: config}
+	for _, origin := range config.AllowedOrigins {
+		if origin == "*" {
+			c.allowAllOrigins = true
+			break
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+				c.handlePreflight(w, req)
+				return
+			}
+			c.handleActual(w, req)
+			if next != nil {
+				next.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+type cors struct {
+	config          CORSConfig
+	allowAllOrigins bool
+}
+
+func (c *cors) isOriginAllowed(origin string) bool {
+	if c.allowAllOrigins {
+		return true
+	}
+	for _, allowed := range c.config.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// setOrigin sets the Access-Control-Allow-Origin header, it reports false if
+// the origin is not allowed.
+func (c *cors) setOrigin(w http.ResponseWriter, req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" || !c.isOriginAllowed(origin) {
+		return false
+	}
+	h := w.Header()
+	// The wildcard is not allowed for credentialed requests.
+	if c.allowAllOrigins && !c.config.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.config.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+func (c *cors) handleActual(w http.ResponseWriter, req *http.Request) {
+	w.Header().Add("Vary", "Origin")
+	c.setOrigin(w, req)
+}
+
+func (c *cors) handlePreflight(w http.ResponseWriter, req *http.Request) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	if !c.setOrigin(w, req) {
+		return
+	}
+
+	methods := c.config.AllowedMethods
+	if len(methods) == 0 {
+		methods = GetAllowedMethods(req)
+	}
+	if len(methods) > 0 {
+		h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	} else if allow := h.Get("Allow"); allow != "" {
+		h.Set("Access-Control-Allow-Methods", allow)
+	}
+
+	if len(c.config.AllowedHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(c.config.AllowedHeaders, ", "))
+	} else if headers := req.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		h.Set("Access-Control-Allow-Headers", headers)
+	}
+
+	if c.config.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(c.config.MaxAge))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	handle := func(http.ResponseWriter, *http.Request) {}
+
+	tests := []struct {
+		config         CORSConfig
+		origin         string
+		requestHeaders string
+		code           int
+		allowOrigin    string
+		allowMethods   string
+		allowHeaders   string
+		credentials    string
+		maxAge         string
+	}{
+		{
+			config: CORSConfig{AllowedOrigins: []string{"https://example.com"}},
+			origin: "https://example.com", code: http.StatusNoContent,
+			allowOrigin: "https://example.com", allowMethods: "OPTIONS, POST, PUT",
+		},
+		{
+			config: CORSConfig{AllowedOrigins: []string{"https://example.com"}},
+			origin: "https://evil.com", code: http.StatusOK,
+		},
+		{
+			config: CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"PUT"}, MaxAge: 600},
+			origin: "https://example.com", requestHeaders: "X-Token", code: http.StatusNoContent,
+			allowOrigin: "*", allowMethods: "PUT", allowHeaders: "X-Token", maxAge: "600",
+		},
+		{
+			config: CORSConfig{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"X-Token", "X-Foo"}, AllowCredentials: true},
+			origin: "https://example.com", requestHeaders: "X-Bar", code: http.StatusNoContent,
+			allowOrigin: "https://example.com", allowMethods: "OPTIONS, POST, PUT", allowHeaders: "X-Token, X-Foo", credentials: "true",
+		},
+	}
+	for i, test := range tests {
+		router := NewRouter()
+		router.Post("/path", handle)
+		router.Put("/path", handle)
+		router.GlobalOPTIONS = CORS(test.config)(nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodOptions, "/path", nil)
+		req.Header.Set("Origin", test.origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+		if test.requestHeaders != "" {
+			req.Header.Set("Access-Control-Request-Headers", test.requestHeaders)
+		}
+		router.ServeHTTP(w, req)
+
+		if w.Code != test.code {
+			t.Errorf("%d: expected code %d, got %d", i, test.code, w.Code)
+		}
+		headers := map[string]string{
+			"Access-Control-Allow-Origin":      test.allowOrigin,
+			"Access-Control-Allow-Methods":     test.allowMethods,
+			"Access-Control-Allow-Headers":     test.allowHeaders,
+			"Access-Control-Allow-Credentials": test.credentials,
+			"Access-Control-Max-Age":           test.maxAge,
+		}
+		for name, expected := range headers {
+			if value := w.Header().Get(name); value != expected {
+				t.Errorf("%d: expected %s header %q, got %q", i, name, expected, value)
+			}
+		}
+	}
+}
+
+func TestCORSActual(t *testing.T) {
+	router := NewRouter()
+	router.Use(CORS(CORSConfig{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true}))
+	router.Get("/path", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	tests := []struct {
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{"https://example.com", "https://example.com", "true"},
+		{"https://EXAMPLE.com", "https://EXAMPLE.com", "true"},
+		{"https://evil.com", "", ""},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/path", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		router.ServeHTTP(w, req)
+		if w.Body.String() != "hello" {
+			t.Errorf("%q: expected body %q, got %q", test.origin, "hello", w.Body)
+		}
+		if value := w.Header().Get("Access-Control-Allow-Origin"); value != test.allowOrigin {
+			t.Errorf("%q: expected Access-Control-Allow-Origin %q, got %q", test.origin, test.allowOrigin, value)
+		}
+		if value := w.Header().Get("Access-Control-Allow-Credentials"); value != test.credentials {
+			t.Errorf("%q: expected Access-Control-Allow-Credentials %q, got %q", test.origin, test.credentials, value)
+		}
+		if value := w.Header().Get("Vary"); value != "Origin" {
+			t.Errorf("%q: expected Vary header %q, got %q", test.origin, "Origin", value)
+		}
+	}
+}
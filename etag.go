@@ -0,0 +1,135 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag returns a middleware that buffers the responses of GET requests and
+// sets a weak ETag computed from the hash of the body, 304 Not Modified is
+// replied if the ETag matches the "If-None-Match" header of the request. The
+// ETag set by the handler is respected rather than computed.
+//
+// The responses of other status codes than 200 OK are written as is, and the
+// streaming responses, which are flushed explicitly, are not buffered since
+// the first flush, and therefore have no ETag.
+func ETag() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet {
+				next.ServeHTTP(w, req)
+				return
+			}
+			ew := &etagResponseWriter{responseWriter: newResponseWriter(w)}
+			next.ServeHTTP(exposeInterfaces(ew), req)
+			ew.close(req)
+		})
+	}
+}
+
+// etagResponseWriter buffers the response until it is flushed or the handler
+// returns.
+type etagResponseWriter struct {
+	*responseWriter
+	code      int
+	buf       []byte
+	streaming bool
+}
+
+func (w *etagResponseWriter) WriteHeader(code int) {
+	if w.streaming {
+		w.responseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *etagResponseWriter) Write(p []byte) (int, error) {
+	if w.streaming {
+		return w.responseWriter.Write(p)
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// ReadFrom implements io.ReaderFrom by Write, so that the response is
+// buffered.
+func (w *etagResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(writerOnly{w}, src)
+}
+
+// Flush implements http.Flusher, the buffered response is written without
+// ETag, and the later writes are not buffered.
+func (w *etagResponseWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		w.writeBuffered()
+	}
+	w.responseWriter.Flush()
+}
+
+func (w *etagResponseWriter) writeBuffered() {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.responseWriter.WriteHeader(w.code)
+	if len(w.buf) > 0 {
+		w.responseWriter.Write(w.buf)
+	}
+	w.buf = nil
+}
+
+func (w *etagResponseWriter) close(req *http.Request) {
+	if w.streaming {
+		return
+	}
+	if w.code == 0 {
+		// nothing was written, leaves the default response to the server.
+		return
+	}
+	if w.code == http.StatusOK {
+		h := w.Header()
+		etag := h.Get("ETag")
+		if etag == "" {
+			hash := fnv.New64a()
+			hash.Write(w.buf)
+			etag = `W/"` + strconv.FormatUint(hash.Sum64(), 16) + `"`
+			h.Set("ETag", etag)
+		}
+		if etagMatch(req.Header.Get("If-None-Match"), etag) {
+			h.Del("Content-Type")
+			h.Del("Content-Length")
+			w.responseWriter.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.writeBuffered()
+}
+
+// etagMatch reports whether the "If-None-Match" header matches the ETag by
+// the weak comparison.
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
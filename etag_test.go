@@ -0,0 +1,99 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	router := NewRouter()
+	router.Use(ETag())
+	router.Get("/hello", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		fmt.Fprint(w, "hello ", req.URL.Query().Get("name"))
+	})
+	router.Get("/tagged", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "tagged")
+	})
+	router.Get("/missing", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Reason", "gone")
+		http.Error(w, "missing", http.StatusNotFound)
+	})
+	router.Post("/hello", echoHandler("posted").ServeHTTP)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello?name=foo", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "hello foo" || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("unexpected response: Code=%d, Body=%q, ETag=%q", w.Code, w.Body, etag)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello?name=bar", nil))
+	if other := w.Header().Get("ETag"); other == etag || other == "" {
+		t.Errorf("expected a different ETag of a different body, got %q", other)
+	}
+
+	tests := []struct {
+		method      string
+		path        string
+		ifNoneMatch string
+		code        int
+		body        string
+		etag        string
+	}{
+		{http.MethodGet, "/hello?name=foo", etag, http.StatusNotModified, "", etag},
+		{http.MethodGet, "/hello?name=foo", strings.TrimPrefix(etag, "W/"), http.StatusNotModified, "", etag},
+		{http.MethodGet, "/hello?name=foo", `"other", ` + etag, http.StatusNotModified, "", etag},
+		{http.MethodGet, "/hello?name=foo", "*", http.StatusNotModified, "", etag},
+		{http.MethodGet, "/hello?name=foo", `"other"`, http.StatusOK, "hello foo", etag},
+		{http.MethodGet, "/tagged", `W/"v1"`, http.StatusNotModified, "", `"v1"`},
+		{http.MethodGet, "/tagged", `"v2"`, http.StatusOK, "tagged", `"v1"`},
+		{http.MethodGet, "/missing", "*", http.StatusNotFound, "missing\n", ""},
+		{http.MethodPost, "/hello", "*", http.StatusOK, "posted", ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		req.Header.Set("If-None-Match", test.ifNoneMatch)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code || w.Body.String() != test.body || w.Header().Get("ETag") != test.etag {
+			t.Errorf("%s %s %q: unexpected response: Code=%d, Body=%q, ETag=%q", test.method, test.path, test.ifNoneMatch, w.Code, w.Body, w.Header().Get("ETag"))
+		}
+		if test.code == http.StatusNotModified && w.Header().Get("Content-Type") != "" {
+			t.Errorf("%s %q: expected no Content-Type of 304 response", test.path, test.ifNoneMatch)
+		}
+		if test.code == http.StatusNotFound && w.Header().Get("X-Reason") != "gone" {
+			t.Errorf("%s: expected the headers are preserved", test.path)
+		}
+	}
+	if w.Header().Get("Cache-Control") != "no-cache" {
+		t.Error("expected the headers are preserved")
+	}
+}
+
+func TestETagFlush(t *testing.T) {
+	handler := ETag()(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, "data: 2\n\n")
+	}))
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", "*")
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted || w.Body.String() != "data: 1\n\ndata: 2\n\n" || !w.Flushed {
+		t.Errorf("unexpected response: Code=%d, Body=%q, Flushed=%t", w.Code, w.Body, w.Flushed)
+	}
+	if etag := w.Header().Get("ETag"); etag != "" {
+		t.Errorf("expected no ETag of streaming response, got %q", etag)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// Explain returns a step-by-step trace of matching the given method and path,
+// including the traversed tree nodes, where the traversal diverged, and how
+// the request would be served, such as a redirection, a fallback or 404.
+// The routes of hosts are excluded, and the request specific options, such as
+// UnescapePathValues, are not applied. It is a debugging tool, not intended to
+// be called while serving.
+func (r *Router) Explain(method, path string) string {
+	var buf bytes.Buffer
+	trace := func(format string, args ...interface{}) {
+		fmt.Fprintf(&buf, format+"\n", args...)
+	}
+
+	trace("explaining %s %s", method, path)
+	if r.CleanPathBeforeMatch {
+		if cleaned := CleanPath(path); cleaned != path {
+			trace("path is cleaned to %q", cleaned)
+			path = cleaned
+		}
+	}
+
+	if root := r.trees[method]; root == nil {
+		trace("no routes of method %s", method)
+	} else {
+		trace("walking the tree of method %s", method)
+		root.explain(path, trace)
+		if route, ps, tsr := r.getValue(root, path); route != nil {
+			trace("matched route %q with params %v", route.path, paramsOf(ps))
+			return buf.String()
+		} else if method != http.MethodConnect && path != "/" {
+			if tsr && r.RedirectTrailingSlash && !r.StrictSlash {
+				trace("redirects to the path with (without) the trailing slash by RedirectTrailingSlash")
+				return buf.String()
+			}
+			if r.RedirectFixedPath {
+				fixedPath, found := root.findCaseInsensitivePath(CleanPath(path), r.RedirectTrailingSlash && !r.StrictSlash)
+				if found {
+					if route, _, _ := root.getValue(fixedPath, nil); route != nil {
+						trace("redirects to the fixed path %q by RedirectFixedPath", fixedPath)
+						return buf.String()
+					}
+				}
+			}
+		}
+		trace("no route of method %s matches", method)
+	}
+
+	if method == http.MethodHead && r.HandleHEAD {
+		if root := r.trees[http.MethodGet]; root != nil {
+			if route, ps, _ := r.getValue(root, path); route != nil {
+				trace("matched GET route %q with params %v by HandleHEAD", route.path, paramsOf(ps))
+				return buf.String()
+			}
+		}
+	}
+
+	if root := r.trees[MethodAny]; root != nil && method != MethodAny {
+		if route, ps, _ := r.getValue(root, path); route != nil {
+			trace("matched route %q of MethodAny with params %v", route.path, paramsOf(ps))
+			return buf.String()
+		}
+	}
+
+	if method == http.MethodOptions && r.HandleOPTIONS {
+		if allow := r.allowed(path, http.MethodOptions); allow != "" {
+			trace("replies to OPTIONS automatically, allowed methods: %s", allow)
+			return buf.String()
+		}
+	} else if r.HandleMethodNotAllowed {
+		if allow := r.allowed(path, method); allow != "" {
+			trace("replies 405 Method Not Allowed, allowed methods: %s", allow)
+			return buf.String()
+		}
+	}
+
+	trace("replies 404 Not Found")
+	return buf.String()
+}
+
+func paramsOf(ps *Params) Params {
+	if ps == nil {
+		return nil
+	}
+	return *ps
+}
+
+// explain walks the tree like find, and traces the traversed nodes.
+func (n *node) explain(path string, trace func(format string, args ...interface{})) {
+	for {
+		prefix := n.path
+		if len(path) > len(prefix) && path[:len(prefix)] == prefix {
+			path = path[len(prefix):]
+			if prefix != "" {
+				trace("  node %q matched, remaining %q", prefix, path)
+			}
+			if !n.wildChild {
+				child := n.childOf(path[0])
+				if child == nil {
+					trace("  no child of node %q for %q", prefix, path)
+					return
+				}
+				n = child
+				continue
+			}
+
+			n = n.children[0]
+			if n.nType == catchAll {
+				trace("  catch-all %q captured %q", n.path[2:], path)
+				n.traceRoute(trace)
+				return
+			}
+			end := 0
+			for end < len(path) && path[end] != '/' {
+				end++
+			}
+			trace("  param %q captured %q", n.path[1:], path[:end])
+			if end == len(path) {
+				n.traceRoute(trace)
+				return
+			}
+			if len(n.children) == 0 {
+				trace("  no child of param %q for %q", n.path[1:], path[end:])
+				return
+			}
+			path = path[end:]
+			n = n.children[0]
+		} else if path == prefix {
+			trace("  node %q matched the rest of path", prefix)
+			n.traceRoute(trace)
+			return
+		} else {
+			trace("  node %q diverged from %q", prefix, path)
+			return
+		}
+	}
+}
+
+func (n *node) childOf(c byte) *node {
+	for i, index := range []byte(n.indices) {
+		if index == c {
+			return n.children[i]
+		}
+	}
+	return nil
+}
+
+func (n *node) traceRoute(trace func(format string, args ...interface{})) {
+	if n.route == nil {
+		trace("  no route registered at the node")
+		return
+	}
+	trace("  found route %q", n.route.path)
+	if len(n.route.constraints) > 0 || len(n.route.segments) > 0 {
+		trace("  route %q has constraints or compound segments to check", n.route.path)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRouterExplain(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/users/:id", echoHandler(""), RouteConstraint("id", regexp.MustCompile(`\d+`)))
+	router.Handle(http.MethodGet, "/posts/", echoHandler(""))
+	router.Handle(http.MethodGet, "/static/*filepath", echoHandler(""))
+	router.Handle(http.MethodPost, "/comments", echoHandler(""))
+
+	tests := []struct {
+		method string
+		path   string
+		lines  []string
+	}{
+		{http.MethodGet, "/users/1", []string{`param "id" captured "1"`, `matched route "/users/:id" with params [{id 1}]`}},
+		{http.MethodGet, "/users/foo", []string{`found route "/users/:id"`, "has constraints", "replies 404 Not Found"}},
+		{http.MethodGet, "/posts", []string{"redirects to the path with (without) the trailing slash"}},
+		{http.MethodGet, "/POSTS/", []string{`redirects to the fixed path "/posts/"`}},
+		{http.MethodGet, "/static/js/app.js", []string{`catch-all "filepath" captured "/js/app.js"`}},
+		{http.MethodGet, "/comments", []string{"no route of method GET matches", "405 Method Not Allowed, allowed methods: OPTIONS, POST"}},
+		{http.MethodPut, "/comments", []string{"no routes of method PUT"}},
+		{http.MethodOptions, "/comments", []string{"replies to OPTIONS automatically"}},
+	}
+	for _, test := range tests {
+		trace := router.Explain(test.method, test.path)
+		for _, line := range test.lines {
+			if !strings.Contains(trace, line) {
+				t.Errorf("%s %s: expected trace containing %q, got:\n%s", test.method, test.path, line, trace)
+			}
+		}
+	}
+}
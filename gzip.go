@@ -0,0 +1,216 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultGzipMinSize is the minimum response size to compress used by Gzip.
+const DefaultGzipMinSize = 1024
+
+// GzipOptions contains the options of the Gzip middleware.
+type GzipOptions struct {
+	// Level is the compression level, such as gzip.DefaultCompression and
+	// gzip.BestSpeed.
+	Level int
+
+	// MinSize is the minimum size of the response body to compress, the
+	// smaller responses are written uncompressed. The response is buffered
+	// until MinSize bytes are written or it is flushed.
+	MinSize int
+
+	// ExcludedContentTypes is a list of content type prefixes which are not
+	// compressed, such as "image/", if it is nil, the already compressed
+	// types, such as images, videos and archives, are excluded.
+	ExcludedContentTypes []string
+}
+
+var defaultGzipExcludedContentTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/gzip", "application/x-gzip", "application/zip",
+	"application/x-7z-compressed", "application/x-rar-compressed",
+	"application/x-bzip2", "application/x-xz", "application/zstd",
+	"application/octet-stream", "application/pdf",
+}
+
+// Gzip returns a middleware that compresses the responses by gzip with the
+// given level, if the client accepts gzip encoding, the responses smaller
+// than DefaultGzipMinSize are not compressed, see GzipWithOptions.
+func Gzip(level int) Middleware {
+	return GzipWithOptions(GzipOptions{Level: level, MinSize: DefaultGzipMinSize})
+}
+
+// GzipWithOptions is like Gzip, but applies the given options.
+//
+// The responses which already have a Content-Encoding, the excluded content
+// types, and the responses without body, such as HEAD requests and 304 Not
+// Modified, are not compressed. Flushing the response flushes the compressed
+// data, so that streaming responses, such as server-sent events, keep working.
+// It panics if the level is invalid.
+func GzipWithOptions(opts GzipOptions) Middleware {
+	if _, err := gzip.NewWriterLevel(ioutil.Discard, opts.Level); err != nil {
+		panic(err.Error())
+	}
+	excluded := opts.ExcludedContentTypes
+	if excluded == nil {
+		excluded = defaultGzipExcludedContentTypes
+	}
+	pool := &sync.Pool{
+		New: func() interface{} {
+			gz, _ := gzip.NewWriterLevel(ioutil.Discard, opts.Level)
+			return gz
+		},
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !acceptsEncoding(req.Header.Get("Accept-Encoding"), "gzip") || req.Method == http.MethodHead {
+				next.ServeHTTP(w, req)
+				return
+			}
+			gw := &gzipResponseWriter{
+				responseWriter: newResponseWriter(w),
+				pool:           pool,
+				minSize:        opts.MinSize,
+				excluded:       excluded,
+			}
+			defer gw.close()
+			next.ServeHTTP(exposeInterfaces(gw), req)
+		})
+	}
+}
+
+// gzipResponseWriter buffers the response until it decides whether to
+// compress the response.
+type gzipResponseWriter struct {
+	*responseWriter
+	pool     *sync.Pool
+	minSize  int
+	excluded []string
+
+	code    int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minSize {
+			return len(p), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.responseWriter.Write(p)
+}
+
+// ReadFrom implements io.ReaderFrom by Write, so that the response is
+// compressed.
+func (w *gzipResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(writerOnly{w}, src)
+}
+
+// Flush implements http.Flusher, the buffered response is compressed unless
+// it is excluded, regardless of its size.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		w.minSize = 0
+		w.decide()
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.responseWriter.Flush()
+}
+
+// decide writes the header and the buffered body, the response is compressed
+// if it is large enough and compressible.
+func (w *gzipResponseWriter) decide() error {
+	w.decided = true
+	h := w.Header()
+	if len(w.buf) > 0 && h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.compressible() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.responseWriter)
+	}
+	w.responseWriter.WriteHeader(w.code)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf)
+	} else {
+		_, err = w.responseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+func (w *gzipResponseWriter) compressible() bool {
+	if len(w.buf) < w.minSize {
+		return false
+	}
+	switch w.code {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	ctype := strings.ToLower(h.Get("Content-Type"))
+	for _, prefix := range w.excluded {
+		if strings.HasPrefix(ctype, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *gzipResponseWriter) close() {
+	if !w.decided {
+		if w.code == 0 {
+			// nothing was written, leaves the default response to the server.
+			return
+		}
+		w.decide()
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(ioutil.Discard)
+		w.pool.Put(w.gz)
+		w.gz = nil
+	}
+}
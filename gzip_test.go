@@ -0,0 +1,103 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	large := strings.Repeat("hello world ", 200)
+	tests := []struct {
+		acceptEncoding string
+		ctype          string
+		body           string
+		compressed     bool
+	}{
+		{"gzip", "", large, true},
+		{"gzip, br", "text/plain", large, true},
+		{"", "", large, false},
+		{"gzip;q=0", "", large, false},
+		{"gzip", "", "small", false},
+		{"gzip", "image/png", large, false},
+	}
+	for _, test := range tests {
+		handler := Gzip(gzip.BestSpeed)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if test.ctype != "" {
+				w.Header().Set("Content-Type", test.ctype)
+			}
+			fmt.Fprint(w, test.body)
+		}))
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		handler.ServeHTTP(w, req)
+
+		if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("%q %q: expected Vary header %q, got %q", test.acceptEncoding, test.ctype, "Accept-Encoding", vary)
+		}
+		body := w.Body.String()
+		if test.compressed {
+			if encoding := w.Header().Get("Content-Encoding"); encoding != "gzip" {
+				t.Errorf("%q %q: expected Content-Encoding gzip, got %q", test.acceptEncoding, test.ctype, encoding)
+				continue
+			}
+			gz, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := ioutil.ReadAll(gz)
+			body = string(data)
+			if ctype := w.Header().Get("Content-Type"); ctype == "" {
+				t.Errorf("%q %q: expected Content-Type", test.acceptEncoding, test.ctype)
+			}
+		} else if encoding := w.Header().Get("Content-Encoding"); encoding != "" {
+			t.Errorf("%q %q: expected no Content-Encoding, got %q", test.acceptEncoding, test.ctype, encoding)
+		}
+		if body != test.body {
+			t.Errorf("%q %q: unexpected body %q", test.acceptEncoding, test.ctype, body)
+		}
+	}
+}
+
+func TestGzipFlush(t *testing.T) {
+	handler := Gzip(gzip.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, "data: 2\n\n")
+	}))
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	handler.ServeHTTP(w, req)
+
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if encoding := w.Header().Get("Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("expected Content-Encoding gzip, got %q", encoding)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(gz)
+	if string(data) != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("unexpected body %q", data)
+	}
+}
+
+func TestGzipInvalidLevel(t *testing.T) {
+	if recv := catchPanic(func() { Gzip(100) }); recv == nil {
+		t.Error("an invalid level did not panic")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// healthCheckTimeout is the maximum duration of health checks.
+const healthCheckTimeout = 5 * time.Second
+
+// HealthStatus is the JSON body written by the Health handler.
+type HealthStatus struct {
+	// Status is "ok" if all checks passed, "unavailable" otherwise.
+	Status string `json:"status"`
+
+	// Errors contains the error messages of the failing checks, keyed by
+	// check name.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Health returns a handler that runs the given checks concurrently, and
+// responds 200 OK if all of them passed, 503 Service Unavailable with the
+// failing checks otherwise, the response body is a JSON-encoded HealthStatus:
+//  router.Get("/healthz", clevergo.Health(map[string]func(context.Context) error{
+//  	"database": db.PingContext,
+//  }))
+//
+// The checks are given a context derived from the request context, which is
+// canceled after 5 seconds or at the deadline of the request context,
+// whichever comes first, a check that does not return in time is considered
+// failed with the context error.
+func Health(checks map[string]func(context.Context) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), healthCheckTimeout)
+		defer cancel()
+
+		type result struct {
+			name string
+			err  error
+		}
+		results := make(chan result, len(checks))
+		for name, check := range checks {
+			go func(name string, check func(context.Context) error) {
+				results <- result{name, check(ctx)}
+			}(name, check)
+		}
+
+		status := HealthStatus{Status: "ok"}
+		errs := make(map[string]string)
+		pending := make(map[string]bool, len(checks))
+		for name := range checks {
+			pending[name] = true
+		}
+		for len(pending) > 0 {
+			select {
+			case res := <-results:
+				delete(pending, res.name)
+				if res.err != nil {
+					errs[res.name] = res.err.Error()
+				}
+			case <-ctx.Done():
+				for name := range pending {
+					errs[name] = ctx.Err().Error()
+				}
+				pending = nil
+			}
+		}
+
+		code := http.StatusOK
+		if len(errs) > 0 {
+			code = http.StatusServiceUnavailable
+			status.Status = "unavailable"
+			status.Errors = errs
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(status)
+	}
+}
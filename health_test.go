@@ -0,0 +1,67 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	pass := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("connection refused") }
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		checks  map[string]func(context.Context) error
+		timeout time.Duration
+		code    int
+		body    string
+	}{
+		{nil, 0, http.StatusOK, `{"status":"ok"}` + "\n"},
+		{
+			map[string]func(context.Context) error{"db": pass, "cache": pass},
+			0, http.StatusOK, `{"status":"ok"}` + "\n",
+		},
+		{
+			map[string]func(context.Context) error{"db": pass, "cache": fail},
+			0, http.StatusServiceUnavailable, `{"status":"unavailable","errors":{"cache":"connection refused"}}` + "\n",
+		},
+		{
+			map[string]func(context.Context) error{"db": hang, "cache": fail},
+			10 * time.Millisecond, http.StatusServiceUnavailable,
+			`{"status":"unavailable","errors":{"cache":"connection refused","db":"context deadline exceeded"}}` + "\n",
+		},
+	}
+	for i, test := range tests {
+		router := NewRouter()
+		router.Get("/healthz", Health(test.checks))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+		if test.timeout > 0 {
+			ctx, cancel := context.WithTimeout(req.Context(), test.timeout)
+			defer cancel()
+			req = req.WithContext(ctx)
+		}
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%d: expected code %d, got %d", i, test.code, w.Code)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("%d: expected body %s, got %s", i, test.body, w.Body)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/json; charset=utf-8" {
+			t.Errorf("%d: unexpected content type %q", i, contentType)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net"
+	"strings"
+)
+
+// hostRoutes holds the route trees of a host pattern.
+type hostRoutes struct {
+	pattern string
+	trees   map[string]*node
+}
+
+func isWildcardHost(pattern string) bool {
+	return strings.HasPrefix(pattern, "*.")
+}
+
+// hostRoutes returns the route trees of the given host pattern, creates one if not exists.
+func (r *Router) hostRoutes(pattern string) *hostRoutes {
+	if r.hosts == nil {
+		r.hosts = make(map[string]*hostRoutes)
+	}
+	h, ok := r.hosts[pattern]
+	if !ok {
+		h = &hostRoutes{pattern: pattern, trees: make(map[string]*node)}
+		r.hosts[pattern] = h
+	}
+	return h
+}
+
+// matchHost returns the route trees of the given request host, exact host
+// takes priority over wildcard hosts, and the longest wildcard host wins.
+// The returned param holds the subdomain if the matched host is a wildcard host.
+func (r *Router) matchHost(host string) (*hostRoutes, *Param) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	if h, ok := r.hosts[host]; ok {
+		return h, nil
+	}
+
+	var matched *hostRoutes
+	var subdomain string
+	for pattern, h := range r.hosts {
+		if !isWildcardHost(pattern) {
+			continue
+		}
+		suffix := pattern[1:] // ".example.com"
+		if len(host) > len(suffix) && strings.HasSuffix(host, suffix) &&
+			(matched == nil || len(pattern) > len(matched.pattern)) {
+			matched = h
+			subdomain = host[:len(host)-len(suffix)]
+		}
+	}
+	if matched == nil {
+		return nil, nil
+	}
+	return matched, &Param{Key: "subdomain", Value: subdomain}
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterHost(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/", echoHandler("default"))
+	router.Handle(http.MethodGet, "/users", echoHandler("default users"))
+
+	api := router.Host("API.example.com")
+	api.Handle(http.MethodGet, "/", echoHandler("api"), RouteName("home"))
+	api.Group("/v1").Handle(http.MethodPost, "/users", echoHandler("api users"))
+
+	router.Host("*.example.com").HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		ps := GetParams(r)
+		fmt.Fprintf(w, "%s user %s", ps.Get("subdomain"), ps.Get("id"))
+	})
+	router.Host("*.foo.example.com").HandleFunc(http.MethodGet, "/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "foo %s", GetParams(r).Get("subdomain"))
+	})
+	router.HandleFunc(http.MethodGet, "/about", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "about %s", GetParams(r).Get("subdomain"))
+	}, RouteHost("*.example.org"))
+
+	tests := []struct {
+		method string
+		host   string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "example.com", "/", http.StatusOK, "default"},
+		{http.MethodGet, "example.com", "/users", http.StatusOK, "default users"},
+		{http.MethodGet, "api.example.com", "/", http.StatusOK, "api"},
+		{http.MethodGet, "Api.Example.com:8080", "/", http.StatusOK, "api"},
+		{http.MethodGet, "api.example.com", "/users", http.StatusNotFound, "404 page not found\n"},
+		{http.MethodPost, "api.example.com", "/v1/users", http.StatusOK, "api users"},
+		{http.MethodGet, "api.example.com", "/v1/users", http.StatusMethodNotAllowed, "Method Not Allowed\n"},
+		{http.MethodGet, "bar.example.com", "/users/1", http.StatusOK, "bar user 1"},
+		{http.MethodGet, "a.b.example.com", "/users/1", http.StatusOK, "a.b user 1"},
+		{http.MethodGet, "bar.foo.example.com", "/", http.StatusOK, "foo bar"},
+		{http.MethodGet, "bar.example.com", "/", http.StatusNotFound, "404 page not found\n"},
+		{http.MethodGet, "www.example.org", "/about", http.StatusOK, "about www"},
+		{http.MethodGet, "example.org", "/about", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, nil)
+		req.Host = test.host
+		router.ServeHTTP(w, req)
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("%s %s%s: unexpected response: Code=%d, Body=%q", test.method, test.host, test.path, w.Code, w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
+	req.Host = "api.example.com"
+	router.ServeHTTP(w, req)
+	if allow := w.Header().Get("Allow"); allow != "OPTIONS, POST" {
+		t.Error("unexpected Allow header value: " + allow)
+	}
+
+	url, err := router.URL("home")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if url.String() != "/" {
+		t.Errorf("expected url %q, got %q", "/", url)
+	}
+
+	hosts := map[string]bool{}
+	for _, route := range router.Routes() {
+		hosts[route.Host] = true
+	}
+	for _, host := range []string{"", "api.example.com", "*.example.com", "*.foo.example.com", "*.example.org"} {
+		if !hosts[host] {
+			t.Errorf("routes of host %q are missing", host)
+		}
+	}
+}
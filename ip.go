@@ -0,0 +1,91 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RemoteIP returns the IP of the request remote address, the port is dropped.
+// The proxy headers, such as "X-Forwarded-For", are not trusted.
+func RemoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// ClientIP returns the IP of the client of the request, the proxy headers are
+// only used if the remote address is one of the given trusted proxies, which
+// are either IPs or CIDRs, such as "10.0.0.0/8" and "::1".
+//
+// The "X-Forwarded-For" chain is walked from right to left, the first IP
+// which is not a trusted proxy is the client IP, the leftmost IP is returned
+// if all of them are trusted. "X-Real-IP" is used if there is no valid
+// "X-Forwarded-For" header. Otherwise the remote IP is returned, see RemoteIP.
+// The IPv6 addresses may be bracketed and have ports, such as "[::1]:8080",
+// and the returned IP is in the canonical form.
+func ClientIP(req *http.Request, trustedProxies []string) string {
+	remote := parseIP(req.RemoteAddr)
+	if remote == nil {
+		return RemoteIP(req)
+	}
+	trusted := func(ip net.IP) bool {
+		for _, proxy := range trustedProxies {
+			if strings.IndexByte(proxy, '/') >= 0 {
+				if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(ip) {
+					return true
+				}
+			} else if p := net.ParseIP(proxy); p != nil && p.Equal(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	if !trusted(remote) {
+		return remote.String()
+	}
+
+	var client net.IP
+	if values := req.Header["X-Forwarded-For"]; len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := parseIP(hops[i])
+			if ip == nil {
+				// the chain before an invalid hop is not reliable.
+				break
+			}
+			client = ip
+			if !trusted(ip) {
+				break
+			}
+		}
+	}
+	if client == nil {
+		client = parseIP(req.Header.Get("X-Real-IP"))
+	}
+	if client == nil {
+		return remote.String()
+	}
+	return client.String()
+}
+
+// parseIP parses the IP of the given address, which may be bracketed and have
+// a port, nil is returned if it is invalid.
+func parseIP(addr string) net.IP {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	// drops the zone of IPv6 address, such as "fe80::1%eth0".
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	return net.ParseIP(addr)
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRemoteIP(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		expected   string
+	}{
+		{"192.0.2.1:1234", "192.0.2.1"},
+		{"[2001:db8::1]:1234", "2001:db8::1"},
+		{"192.0.2.1", "192.0.2.1"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = test.remoteAddr
+		if ip := RemoteIP(req); ip != test.expected {
+			t.Errorf("RemoteIP(%q): expected %q, got %q", test.remoteAddr, test.expected, ip)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trustedProxies := []string{"10.0.0.0/8", "::1", "2001:db8:ffff::/48"}
+	tests := []struct {
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		expected   string
+	}{
+		// untrusted peer, headers are ignored.
+		{"192.0.2.1:1234", []string{"203.0.113.1"}, "203.0.113.2", "192.0.2.1"},
+		{"[2001:db8::1]:1234", []string{"203.0.113.1"}, "", "2001:db8::1"},
+		// trusted peer.
+		{"10.0.0.1:1234", []string{"203.0.113.1"}, "", "203.0.113.1"},
+		{"[::1]:1234", []string{"203.0.113.1"}, "", "203.0.113.1"},
+		{"[2001:db8:ffff::1]:1234", []string{"[2001:db8::2]:8080"}, "", "2001:db8::2"},
+		{"10.0.0.1:1234", []string{"2001:DB8::2"}, "", "2001:db8::2"},
+		{"10.0.0.1:1234", []string{"203.0.113.1:8080"}, "", "203.0.113.1"},
+		// chains are walked from right to left, skipping the trusted proxies.
+		{"10.0.0.1:1234", []string{"198.51.100.1, 203.0.113.1, 10.0.0.2"}, "", "203.0.113.1"},
+		{"10.0.0.1:1234", []string{"198.51.100.1", "203.0.113.1, 10.0.0.2"}, "", "203.0.113.1"},
+		{"10.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "", "10.0.0.3"},
+		{"10.0.0.1:1234", []string{"spoofed, 203.0.113.1"}, "", "203.0.113.1"},
+		{"10.0.0.1:1234", []string{"203.0.113.1, unknown, 10.0.0.2"}, "", "10.0.0.2"},
+		// X-Real-IP.
+		{"10.0.0.1:1234", nil, "203.0.113.2", "203.0.113.2"},
+		{"10.0.0.1:1234", []string{"unknown"}, "[2001:db8::3]", "2001:db8::3"},
+		{"10.0.0.1:1234", nil, "invalid", "10.0.0.1"},
+		{"10.0.0.1:1234", nil, "", "10.0.0.1"},
+		// invalid remote address.
+		{"invalid", []string{"203.0.113.1"}, "", "invalid"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = test.remoteAddr
+		for _, xff := range test.xff {
+			req.Header.Add("X-Forwarded-For", xff)
+		}
+		if test.xRealIP != "" {
+			req.Header.Set("X-Real-IP", test.xRealIP)
+		}
+		if ip := ClientIP(req, trustedProxies); ip != test.expected {
+			t.Errorf("ClientIP(%q, %v, %q): expected %q, got %q", test.remoteAddr, test.xff, test.xRealIP, test.expected, ip)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.1")
+	if ip := ClientIP(req, nil); ip != "10.0.0.1" {
+		t.Errorf("expected remote IP if no proxies are trusted, got %q", ip)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogEntry contains the information of a request logged by the Logger middleware.
+type LogEntry struct {
+	Request *http.Request
+	Method  string
+	Path    string
+
+	// Route is the pattern of the matched route, such as "/users/:id", it is
+	// empty if the route is unknown, see Logger.
+	Route string
+
+	Status   int
+	Size     int
+	Duration time.Duration
+}
+
+// LoggerOptions contains the options of the Logger middleware.
+type LoggerOptions struct {
+	// Output is the destination of log lines, os.Stderr is used if it is nil.
+	// The writes of a middleware are serialized.
+	Output io.Writer
+
+	// Format returns the log line of the entry, a newline is appended if
+	// missing. The default format is:
+	//  GET /users/1 /users/:id 200 1.234ms
+	Format func(entry LogEntry) string
+}
+
+// Logger returns a middleware that logs the method, path, matched route
+// pattern, status code and duration of requests.
+//
+// The route pattern is retrieved by GetRoute, which requires turning on
+// Router.SaveMatchedRoute and registering the middleware by Router.Use or
+// as a route middleware, "-" is logged if the route is unknown.
+func Logger(opts LoggerOptions) Middleware {
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	format := opts.Format
+	if format == nil {
+		format = formatLogEntry
+	}
+
+	var mu sync.Mutex
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			rw := newResponseWriter(w)
+			next.ServeHTTP(exposeInterfaces(rw), req)
+
+			entry := LogEntry{
+				Request:  req,
+				Method:   req.Method,
+				Path:     req.URL.Path,
+				Status:   rw.Status(),
+				Size:     rw.Size(),
+				Duration: time.Since(start),
+			}
+			if entry.Status == 0 {
+				entry.Status = http.StatusOK
+			}
+			if route := GetRoute(req); route != nil {
+				entry.Route = route.Pattern()
+			}
+
+			line := format(entry)
+			if len(line) == 0 || line[len(line)-1] != '\n' {
+				line += "\n"
+			}
+			mu.Lock()
+			io.WriteString(output, line)
+			mu.Unlock()
+		})
+	}
+}
+
+func formatLogEntry(entry LogEntry) string {
+	route := entry.Route
+	if route == "" {
+		route = "-"
+	}
+	return fmt.Sprintf("%s %s %s %d %s", entry.Method, entry.Path, route, entry.Status, entry.Duration)
+}
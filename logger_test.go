@@ -0,0 +1,67 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	router := NewRouter()
+	router.SaveMatchedRoute = true
+	router.Use(Logger(LoggerOptions{Output: &buf}))
+	router.Get("/users/:id", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("user"))
+	})
+	router.Post("/users", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	router.Delete("/users/:id", func(w http.ResponseWriter, _ *http.Request) {})
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/users/1"},
+		{http.MethodPost, "/users"},
+		{http.MethodDelete, "/users/2"},
+		{http.MethodGet, "/nope"},
+	}
+	for _, r := range requests {
+		req, _ := http.NewRequest(r.method, r.path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	expected := regexp.MustCompile(`^GET /users/1 /users/:id 200 \S+
+POST /users /users 201 \S+
+DELETE /users/2 /users/:id 200 \S+
+$`)
+	if !expected.MatchString(buf.String()) {
+		t.Errorf("unexpected log output:\n%s", buf.String())
+	}
+}
+
+func TestLoggerFormat(t *testing.T) {
+	var buf bytes.Buffer
+	handler := Logger(LoggerOptions{
+		Output: &buf,
+		Format: func(entry LogEntry) string {
+			return fmt.Sprintf("%s %s %q %d %d", entry.Method, entry.Path, entry.Route, entry.Status, entry.Size)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "teapot", http.StatusTeapot)
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/tea", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if expected := "GET /tea \"\" 418 7\n"; buf.String() != expected {
+		t.Errorf("expected log output %q, got %q", expected, buf.String())
+	}
+}
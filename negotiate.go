@@ -0,0 +1,84 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// negotiate returns the route of which media type is the most acceptable by
+// the Accept header of the request, the earlier registered route wins a tie.
+// The route is returned if the request has no Accept header, nil is returned
+// if no media type is acceptable.
+func negotiate(req *http.Request, route *Route, variants []*Route) *Route {
+	accept := req.Header.Get("Accept")
+	if accept == "" {
+		return route
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := (*Route)(nil), 0.0
+	for i := -1; i < len(variants); i++ {
+		candidate := route
+		if i >= 0 {
+			candidate = variants[i]
+		}
+		if q := acceptQuality(ranges, candidate.accept); q > bestQ {
+			best, bestQ = candidate, q
+		}
+	}
+	return best
+}
+
+// mediaRange is a media range of the Accept header.
+type mediaRange struct {
+	value string
+	q     float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		value, q := part, 1.0
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			value = part[:i]
+			for _, param := range strings.Split(part[i+1:], ";") {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						q = v
+					}
+				}
+			}
+		}
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" {
+			ranges = append(ranges, mediaRange{value: value, q: q})
+		}
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of the given media type, the most specific
+// matching range takes precedence, such as "text/html" over "text/*".
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.value == mediaType:
+			s = 2
+		case strings.HasSuffix(r.value, "/*") && strings.HasPrefix(mediaType, r.value[:len(r.value)-1]):
+			s = 1
+		case r.value == "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
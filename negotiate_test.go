@@ -0,0 +1,77 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteAccept(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/users", echoHandler("json"), RouteAccept("application/json"), RouteName("users"))
+	router.Handle(http.MethodGet, "/users", echoHandler("html"), RouteAccept("text/html"), RouteName("users.html"))
+	router.Handle(http.MethodGet, "/users", echoHandler("csv"), RouteAccept("Text/CSV"))
+
+	tests := []struct {
+		accept string
+		code   int
+		body   string
+	}{
+		{"", http.StatusOK, "json"},
+		{"*/*", http.StatusOK, "json"},
+		{"text/html", http.StatusOK, "html"},
+		{"text/html;q=0.8, application/json;q=0.9", http.StatusOK, "json"},
+		{"text/*", http.StatusOK, "html"},
+		{"text/*, text/html;q=0", http.StatusOK, "csv"},
+		{"text/csv, */*;q=0.1", http.StatusOK, "csv"},
+		{"image/png", http.StatusNotAcceptable, ""},
+		{"application/json;q=0", http.StatusNotAcceptable, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%q: expected status code %d, got %d", test.accept, test.code, w.Code)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("%q: expected Vary header %q, got %q", test.accept, "Accept", vary)
+		}
+		if test.code == http.StatusOK && w.Body.String() != test.body {
+			t.Errorf("%q: expected body %q, got %q", test.accept, test.body, w.Body)
+		}
+	}
+
+	if routes := router.Routes(); len(routes) != 3 {
+		t.Errorf("expected 3 routes, got %d", len(routes))
+	}
+	if _, ok := router.NamedRoute("users.html"); !ok {
+		t.Error("expected named route users.html")
+	}
+
+	// a duplicate media type panics.
+	if recv := catchPanic(func() {
+		router.Handle(http.MethodGet, "/users", echoHandler(""), RouteAccept("text/html"))
+	}); recv == nil {
+		t.Error("registering a duplicate media type did not panic")
+	}
+	// a route without media type still conflicts.
+	if recv := catchPanic(func() {
+		router.Handle(http.MethodGet, "/users", echoHandler(""))
+	}); recv == nil {
+		t.Error("registering a route without media type did not panic")
+	}
+
+	// the variants are removed with the route.
+	if !router.RemoveRoute(http.MethodGet, "/users") {
+		t.Fatal("failed to remove route")
+	}
+	if _, ok := router.NamedRoute("users.html"); ok {
+		t.Error("expected the named route users.html to be removed")
+	}
+}
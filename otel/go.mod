@@ -0,0 +1,11 @@
+module github.com/clevergo/clevergo/otel
+
+go 1.15
+
+require (
+	github.com/clevergo/clevergo v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+)
+
+replace github.com/clevergo/clevergo => ../
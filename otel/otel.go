@@ -0,0 +1,93 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+// Package otel provides an OpenTelemetry tracing middleware of the clevergo
+// router, it is a separate module, so that the router does not depend on
+// OpenTelemetry.
+package otel
+
+import (
+	"net/http"
+
+	"github.com/clevergo/clevergo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware returns a middleware that traces the requests by the given
+// tracer, the span is named after the method and the pattern of the matched
+// route, such as "GET /users/:id", rather than the request path, so that the
+// spans of a route are grouped together. The remote span context is extracted
+// from the request headers by the global propagator, and the request context
+// carries the span, the method, route and status code are recorded as
+// attributes.
+//
+// The middleware is intended for clevergo.Router.Use, and requires
+// clevergo.Router.SaveMatchedRoute to be turned on, otherwise the spans are
+// named after the method only.
+func Middleware(tracer trace.Tracer) clevergo.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			name := req.Method
+			attrs := []attribute.KeyValue{attribute.String("http.method", req.Method)}
+			if route := clevergo.GetRoute(req); route != nil {
+				name += " " + route.Pattern()
+				attrs = append(attrs, attribute.String("http.route", route.Pattern()))
+			}
+			ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			rw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, req.WithContext(ctx))
+
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(attribute.Int("http.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
+
+// statusWriter records the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher if the underlying writer does.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, see http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
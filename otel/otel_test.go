@@ -0,0 +1,88 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package otel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clevergo/clevergo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fakeSpan struct {
+	trace.Span
+	name   string
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+	ended  bool
+}
+
+func (s *fakeSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *fakeSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+}
+
+func (s *fakeSpan) End(...trace.SpanEndOption) {
+	s.ended = true
+}
+
+type fakeTracer struct {
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &fakeSpan{Span: trace.SpanFromContext(ctx), name: name, attrs: make(map[attribute.Key]attribute.Value)}
+	config := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(config.Attributes()...)
+	t.spans = append(t.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func TestMiddleware(t *testing.T) {
+	tracer := &fakeTracer{}
+	router := clevergo.NewRouter()
+	router.SaveMatchedRoute = true
+	router.Use(Middleware(tracer))
+	router.Get("/users/:id", func(w http.ResponseWriter, req *http.Request) {
+		if trace.SpanFromContext(req.Context()) != tracer.spans[0] {
+			t.Error("expected the span in the request context")
+		}
+	})
+	router.Get("/error", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/error", nil))
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(tracer.spans))
+	}
+
+	span := tracer.spans[0]
+	if span.name != "GET /users/:id" || !span.ended {
+		t.Errorf("expected ended span %q, got %q", "GET /users/:id", span.name)
+	}
+	if route := span.attrs["http.route"].AsString(); route != "/users/:id" {
+		t.Errorf("expected route attribute %q, got %q", "/users/:id", route)
+	}
+	if code := span.attrs["http.status_code"].AsInt64(); code != http.StatusOK || span.status != codes.Unset {
+		t.Errorf("expected status code %d with unset status, got %d %v", http.StatusOK, code, span.status)
+	}
+
+	span = tracer.spans[1]
+	if code := span.attrs["http.status_code"].AsInt64(); code != http.StatusInternalServerError || span.status != codes.Error {
+		t.Errorf("expected status code %d with error status, got %d %v", http.StatusInternalServerError, code, span.status)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// RegisterPprof registers the handlers of net/http/pprof under the given
+// prefix, such as "/debug/pprof", the index page is served at "prefix/", and
+// the named profiles, such as "prefix/heap", are served by pprof.Handler, so
+// that the handlers work under any prefix. The handlers are dispatched by a
+// catch-all route "prefix/*path" of GET and POST methods.
+// Note that importing net/http/pprof also registers the handlers onto
+// http.DefaultServeMux.
+func RegisterPprof(r *Router, prefix string, opts ...RouteOption) {
+	if prefix == "" || prefix[0] != '/' {
+		panic("path must begin with '/' in path '" + prefix + "'")
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	r.Match([]string{http.MethodGet, http.MethodPost}, prefix+"/*path", servePprof, opts...)
+}
+
+func servePprof(w http.ResponseWriter, req *http.Request) {
+	switch name := strings.TrimPrefix(GetParams(req).Get("path"), "/"); name {
+	case "":
+		pprof.Index(w, req)
+	case "cmdline":
+		pprof.Cmdline(w, req)
+	case "profile":
+		pprof.Profile(w, req)
+	case "symbol":
+		pprof.Symbol(w, req)
+	case "trace":
+		pprof.Trace(w, req)
+	default:
+		pprof.Handler(name).ServeHTTP(w, req)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegisterPprof(t *testing.T) {
+	router := NewRouter()
+	RegisterPprof(router, "/admin/pprof/")
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		contains string
+	}{
+		{http.MethodGet, "/admin/pprof/", http.StatusOK, "heap"},
+		{http.MethodGet, "/admin/pprof", http.StatusMovedPermanently, ""},
+		{http.MethodGet, "/admin/pprof/cmdline", http.StatusOK, ""},
+		{http.MethodPost, "/admin/pprof/symbol", http.StatusOK, "num_symbols"},
+		{http.MethodGet, "/admin/pprof/goroutine?debug=1", http.StatusOK, "goroutine profile"},
+		{http.MethodGet, "/admin/pprof/unknown", http.StatusNotFound, "Unknown profile"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code {
+			t.Errorf("%s %s: expected status code %d, got %d", test.method, test.path, test.code, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), test.contains) {
+			t.Errorf("%s %s: expected body containing %q, got %q", test.method, test.path, test.contains, w.Body)
+		}
+	}
+}
+
+func TestRegisterPprofEmptyCatchAll(t *testing.T) {
+	router := NewRouter()
+	router.MatchEmptyCatchAll = true
+	RegisterPprof(router, "/debug/pprof")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "heap") {
+		t.Errorf("unexpected response: Code=%d, Body=%q", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/clevergo/clevergo/ratelimit
+
+go 1.15
+
+require (
+	github.com/clevergo/clevergo v0.0.0-00010101000000-000000000000
+	golang.org/x/time v0.3.0
+)
+
+replace github.com/clevergo/clevergo => ../
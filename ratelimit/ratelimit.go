@@ -0,0 +1,113 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+// Package ratelimit provides the rate limiting route options of the clevergo
+// router, it is a separate module, so that the router does not depend on
+// golang.org/x/time.
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/clevergo/clevergo"
+	"golang.org/x/time/rate"
+)
+
+// Route is a route option for limiting the requests of a route to
+// the given rate with the given burst size, the requests exceeding the limit
+// are answered with 429 Too Many Requests and a "Retry-After" header.
+// The limiter is per route, the routes registered by Any and Match have their
+// own limiters for each method, see RouteBy for per-client limits.
+func Route(r rate.Limit, burst int) clevergo.RouteOption {
+	return func(route *clevergo.Route) {
+		limiter := rate.NewLimiter(r, burst)
+		clevergo.RouteMiddleware(rateLimit(func(*http.Request) *rate.Limiter {
+			return limiter
+		}))(route)
+	}
+}
+
+// RouteBy is like Route, but limits the requests of each key
+// returned by the given function separately. A limiter which has been idle
+// long enough to refill its burst is evicted, since it is the same as a new
+// one, the limiters are kept for the lifetime of the route if the rate is zero.
+func RouteBy(r rate.Limit, burst int, key func(*http.Request) string) clevergo.RouteOption {
+	return func(route *clevergo.Route) {
+		limiters := newKeyLimiters(r, burst)
+		clevergo.RouteMiddleware(rateLimit(func(req *http.Request) *rate.Limiter {
+			return limiters.get(key(req), time.Now())
+		}))(route)
+	}
+}
+
+// keyLimiters holds the limiters of keys, and evicts the idle ones.
+type keyLimiters struct {
+	mu        sync.Mutex
+	r         rate.Limit
+	burst     int
+	evict     bool // whether the limiters are evicted, false for zero rate.
+	idle      time.Duration
+	lastSweep time.Time
+	limiters  map[string]*keyLimiter
+}
+
+type keyLimiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+func newKeyLimiters(r rate.Limit, burst int) *keyLimiters {
+	l := &keyLimiters{r: r, burst: burst, limiters: make(map[string]*keyLimiter)}
+	l.evict = r > 0
+	if l.evict && r != rate.Inf {
+		l.idle = time.Duration(float64(burst) / float64(r) * float64(time.Second))
+	}
+	return l
+}
+
+// get returns the limiter of the key, and sweeps the idle limiters at most
+// once per idle duration.
+func (l *keyLimiters) get(key string, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.evict && now.Sub(l.lastSweep) >= l.idle {
+		for k, limiter := range l.limiters {
+			if now.Sub(limiter.lastSeen) >= l.idle {
+				delete(l.limiters, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	limiter, ok := l.limiters[key]
+	if !ok {
+		limiter = &keyLimiter{Limiter: rate.NewLimiter(l.r, l.burst)}
+		l.limiters[key] = limiter
+	}
+	limiter.lastSeen = now
+	return limiter.Limiter
+}
+
+func rateLimit(limiter func(*http.Request) *rate.Limiter) clevergo.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			now := time.Now()
+			reservation := limiter(req).ReserveN(now, 1)
+			if !reservation.OK() {
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			if delay := reservation.DelayFrom(now); delay > 0 {
+				reservation.CancelAt(now)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
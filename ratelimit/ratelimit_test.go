@@ -0,0 +1,108 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clevergo/clevergo"
+	"golang.org/x/time/rate"
+)
+
+func ok(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+func TestRoute(t *testing.T) {
+	router := clevergo.NewRouter()
+	router.Match([]string{http.MethodGet, http.MethodPost}, "/", ok, Route(rate.Every(time.Minute), 2))
+
+	tests := []struct {
+		method string
+		code   int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodGet, http.StatusOK},
+		{http.MethodGet, http.StatusTooManyRequests},
+		// each method has its own limiter.
+		{http.MethodPost, http.StatusOK},
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(test.method, "/", nil))
+		if w.Code != test.code {
+			t.Errorf("%d: expected status code %d, got %d", i, test.code, w.Code)
+		}
+		if test.code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+			t.Errorf("%d: expected Retry-After %q, got %q", i, "60", w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestRouteBy(t *testing.T) {
+	router := clevergo.NewRouter()
+	router.Get("/", ok, RouteBy(rate.Every(time.Minute), 1, clevergo.RemoteIP))
+
+	tests := []struct {
+		remoteAddr string
+		code       int
+	}{
+		{"192.0.2.1:1234", http.StatusOK},
+		{"192.0.2.1:5678", http.StatusTooManyRequests},
+		{"192.0.2.2:1234", http.StatusOK},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = test.remoteAddr
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected status code %d, got %d", test.remoteAddr, test.code, w.Code)
+		}
+	}
+
+	// an infinite rate allows all requests regardless of the burst.
+	router.Get("/inf", ok, Route(rate.Inf, 0))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/inf", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRouteByEviction(t *testing.T) {
+	limiters := newKeyLimiters(rate.Every(time.Second), 2)
+	if limiters.idle != 2*time.Second {
+		t.Fatalf("unexpected idle duration %s", limiters.idle)
+	}
+
+	now := time.Now()
+	a := limiters.get("a", now)
+	if !a.AllowN(now, 2) {
+		t.Fatal("expected the burst to be allowed")
+	}
+	if limiters.get("a", now.Add(time.Second)) != a {
+		t.Error("expected the limiter to be kept")
+	}
+	limiters.get("b", now.Add(2500*time.Millisecond))
+	if limiters.get("a", now.Add(3500*time.Millisecond)) != a {
+		t.Error("expected the limiter to be kept before it is refilled")
+	}
+
+	limiters.get("c", now.Add(5*time.Second))
+	if _, ok := limiters.limiters["b"]; ok || len(limiters.limiters) != 2 {
+		t.Errorf("expected the idle limiter to be evicted, got %d limiters", len(limiters.limiters))
+	}
+
+	limiters = newKeyLimiters(0, 1)
+	limiters.get("a", now)
+	limiters.get("b", now.Add(time.Hour))
+	if len(limiters.limiters) != 2 {
+		t.Errorf("expected the limiters of zero rate to be kept, got %d limiters", len(limiters.limiters))
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// DefaultRequestIDHeader is the default header of request IDs used by
+// RequestID.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of the incoming request IDs.
+const maxRequestIDLength = 128
+
+// RequestIDOptions contains the options of the RequestID middleware.
+type RequestIDOptions struct {
+	// Header is the header of request IDs, DefaultRequestIDHeader is used if
+	// it is empty.
+	Header string
+
+	// Generator returns a new request ID, a random UUID (version 4) is
+	// generated if it is nil.
+	Generator func() string
+
+	// IgnoreIncoming discards the request IDs given by clients, which should
+	// be turned on if the server is not behind a trusted proxy.
+	IgnoreIncoming bool
+}
+
+// RequestID returns a middleware that attaches a request ID onto the request
+// context, which can be retrieved by GetRequestID, and echoes it in the
+// response header. The incoming request ID of the header is used if it is
+// valid, that is, it consists of up to 128 printable ASCII characters,
+// otherwise a new one is generated.
+//
+// The request ID can be logged by the Logger middleware with a custom format,
+// which retrieves it by GetRequestID(entry.Request), so that the log lines of
+// a request can be correlated, the RequestID middleware must be registered
+// before the Logger middleware in that case.
+func RequestID(opts RequestIDOptions) Middleware {
+	header := opts.Header
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	generate := opts.Generator
+	if generate == nil {
+		generate = newUUID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id := ""
+			if !opts.IgnoreIncoming {
+				id = req.Header.Get(header)
+			}
+			if !validRequestID(id) {
+				id = generate()
+			}
+			w.Header().Set(header, id)
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), requestIDKey, id)))
+		})
+	}
+}
+
+// GetRequestID returns the request ID attached by the RequestID middleware,
+// an empty string is returned if there is none.
+func GetRequestID(req *http.Request) string {
+	id, _ := req.Context().Value(requestIDKey).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x20 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newUUID returns a random UUID (version 4).
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
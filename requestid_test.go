@@ -0,0 +1,88 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	tests := []struct {
+		opts     RequestIDOptions
+		incoming string
+		expected string
+	}{
+		{RequestIDOptions{}, "abc-123", "abc-123"},
+		{RequestIDOptions{}, "", ""},
+		{RequestIDOptions{}, "bad\nid", ""},
+		{RequestIDOptions{}, strings.Repeat("a", 129), ""},
+		{RequestIDOptions{IgnoreIncoming: true}, "abc-123", ""},
+		{RequestIDOptions{Generator: func() string { return "custom" }}, "", "custom"},
+		{RequestIDOptions{Header: "X-Trace-ID"}, "abc-123", "abc-123"},
+	}
+	for _, test := range tests {
+		header := test.opts.Header
+		if header == "" {
+			header = DefaultRequestIDHeader
+		}
+		var id string
+		handler := RequestID(test.opts)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id = GetRequestID(req)
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.incoming != "" {
+			req.Header.Set(header, test.incoming)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if test.expected == "" {
+			if !uuid.MatchString(id) {
+				t.Errorf("%+v %q: expected a generated UUID, got %q", test.opts, test.incoming, id)
+			}
+		} else if id != test.expected {
+			t.Errorf("%+v %q: expected request ID %q, got %q", test.opts, test.incoming, test.expected, id)
+		}
+		if echoed := w.Header().Get(header); echoed != id {
+			t.Errorf("%+v %q: expected header %q, got %q", test.opts, test.incoming, id, echoed)
+		}
+	}
+
+	if id := GetRequestID(httptest.NewRequest(http.MethodGet, "/", nil)); id != "" {
+		t.Errorf("expected empty request ID, got %q", id)
+	}
+	if newUUID() == newUUID() {
+		t.Error("expected different UUIDs")
+	}
+}
+
+func TestRequestIDLogger(t *testing.T) {
+	var buf bytes.Buffer
+	router := NewRouter()
+	router.Use(
+		RequestID(RequestIDOptions{}),
+		Logger(LoggerOptions{
+			Output: &buf,
+			Format: func(entry LogEntry) string {
+				return fmt.Sprintf("%s %s %d", GetRequestID(entry.Request), entry.Path, entry.Status)
+			},
+		}),
+	)
+	router.Get("/", echoHandler("ok").ServeHTTP)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if buf.String() != "abc-123 / 200\n" {
+		t.Errorf("unexpected log line %q", buf.String())
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps http.ResponseWriter, records the status code and the
+// number of bytes written, and forwards the optional interfaces http.Flusher,
+// http.Hijacker, http.Pusher and io.ReaderFrom to the underlying writer, so
+// that wrapping does not break features such as streaming, WebSocket upgrades
+// and sendfile.
+//
+// It implements all of the optional interfaces, the writers passed to the
+// handlers must be wrapped by exposeInterfaces, so that the handlers only see
+// the interfaces of the underlying writer.
+//
+// The types embedding it must also override ReadFrom if they override Write.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w}
+}
+
+// Status returns the status code of the response, zero is returned if the
+// response was not written.
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+// Size returns the number of bytes of the written response body.
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) base() *responseWriter {
+	return w
+}
+
+// Unwrap returns the underlying writer, it is used by http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush implements http.Flusher, it does nothing if the underlying writer
+// is not a http.Flusher.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+var errHijackNotSupported = errors.New("the underlying response writer does not implement http.Hijacker")
+
+// Hijack implements http.Hijacker, an error is returned if the underlying
+// writer is not a http.Hijacker.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errHijackNotSupported
+}
+
+// Push implements http.Pusher, http.ErrNotSupported is returned if the
+// underlying writer is not a http.Pusher.
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// ReadFrom implements io.ReaderFrom, it uses the ReadFrom of the underlying
+// writer if possible, which may use sendfile.
+func (w *responseWriter) ReadFrom(src io.Reader) (n int64, err error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, src)
+	}
+	w.size += int(n)
+	return n, err
+}
+
+// writerOnly hides the optional interfaces of the writer, such as
+// io.ReaderFrom, to avoid the recursion of io.Copy.
+type writerOnly struct {
+	io.Writer
+}
+
+// wrappedWriter is a writer embedding *responseWriter.
+type wrappedWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	io.ReaderFrom
+	base() *responseWriter
+	Unwrap() http.ResponseWriter
+}
+
+// unwrappedWriter is the methods exposed regardless of the underlying writer,
+// io.ReaderFrom falls back to io.Copy.
+type unwrappedWriter interface {
+	http.ResponseWriter
+	io.ReaderFrom
+	Unwrap() http.ResponseWriter
+}
+
+// exposeInterfaces returns the writer which only implements the optional
+// interfaces http.Flusher, http.Hijacker and http.Pusher implemented by the
+// underlying writer, so that the type assertions of handlers are reliable.
+func exposeInterfaces(w wrappedWriter) http.ResponseWriter {
+	inner := w.base().ResponseWriter
+	for {
+		rw, ok := inner.(interface{ base() *responseWriter })
+		if !ok {
+			break
+		}
+		inner = rw.base().ResponseWriter
+	}
+	_, flusher := inner.(http.Flusher)
+	_, hijacker := inner.(http.Hijacker)
+	_, pusher := inner.(http.Pusher)
+	switch {
+	case flusher && hijacker && pusher:
+		return w
+	case flusher && hijacker:
+		return struct {
+			unwrappedWriter
+			http.Flusher
+			http.Hijacker
+		}{w, w, w}
+	case flusher && pusher:
+		return struct {
+			unwrappedWriter
+			http.Flusher
+			http.Pusher
+		}{w, w, w}
+	case hijacker && pusher:
+		return struct {
+			unwrappedWriter
+			http.Hijacker
+			http.Pusher
+		}{w, w, w}
+	case flusher:
+		return struct {
+			unwrappedWriter
+			http.Flusher
+		}{w, w}
+	case hijacker:
+		return struct {
+			unwrappedWriter
+			http.Hijacker
+		}{w, w}
+	case pusher:
+		return struct {
+			unwrappedWriter
+			http.Pusher
+		}{w, w}
+	}
+	return struct {
+		unwrappedWriter
+	}{w}
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fullResponseWriter implements all the optional interfaces, and records
+// the calls.
+type fullResponseWriter struct {
+	*httptest.ResponseRecorder
+	calls []string
+}
+
+func (w *fullResponseWriter) Flush() {
+	w.calls = append(w.calls, "Flush")
+}
+
+func (w *fullResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.calls = append(w.calls, "Hijack")
+	return nil, nil, nil
+}
+
+func (w *fullResponseWriter) Push(target string, opts *http.PushOptions) error {
+	w.calls = append(w.calls, "Push")
+	return nil
+}
+
+func (w *fullResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.calls = append(w.calls, "ReadFrom")
+	return io.Copy(w.ResponseRecorder, src)
+}
+
+func TestResponseWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := newResponseWriter(recorder)
+	if w.Status() != 0 || w.Size() != 0 {
+		t.Errorf("unexpected status %d and size %d", w.Status(), w.Size())
+	}
+	w.WriteHeader(http.StatusNotFound)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("foo"))
+	w.Write([]byte("bar"))
+	if w.Status() != http.StatusNotFound || w.Size() != 6 {
+		t.Errorf("unexpected status %d and size %d", w.Status(), w.Size())
+	}
+
+	w = newResponseWriter(httptest.NewRecorder())
+	w.Flush()
+	if w.Status() != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Status())
+	}
+	if _, _, err := w.Hijack(); err != errHijackNotSupported {
+		t.Errorf("expected error %v, got %v", errHijackNotSupported, err)
+	}
+	if err := w.Push("/app.js", nil); err != http.ErrNotSupported {
+		t.Errorf("expected error %v, got %v", http.ErrNotSupported, err)
+	}
+}
+
+func TestResponseWriterInterfaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		wrap     func(http.ResponseWriter) http.ResponseWriter
+		readFrom bool
+		body     string
+	}{
+		{"responseWriter", func(w http.ResponseWriter) http.ResponseWriter {
+			return newResponseWriter(w)
+		}, true, "body"},
+		{"headResponseWriter", func(w http.ResponseWriter) http.ResponseWriter {
+			return &headResponseWriter{newResponseWriter(w)}
+		}, false, ""},
+		{"notFoundResponseWriter", func(w http.ResponseWriter) http.ResponseWriter {
+			return &notFoundResponseWriter{responseWriter: newResponseWriter(w)}
+		}, true, "body"},
+		{"fileResponseWriter", func(w http.ResponseWriter) http.ResponseWriter {
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			return &fileResponseWriter{responseWriter: newResponseWriter(w), req: req}
+		}, true, "body"},
+		{"timeoutResponseWriter", func(w http.ResponseWriter) http.ResponseWriter {
+			return &timeoutResponseWriter{responseWriter: newResponseWriter(w), ctx: context.Background()}
+		}, true, "body"},
+	}
+	for _, test := range tests {
+		underlying := &fullResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+		w := test.wrap(underlying)
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Errorf("%s: expected a http.Flusher", test.name)
+			continue
+		}
+		flusher.Flush()
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Errorf("%s: expected a http.Hijacker", test.name)
+			continue
+		}
+		hijacker.Hijack()
+		pusher, ok := w.(http.Pusher)
+		if !ok {
+			t.Errorf("%s: expected a http.Pusher", test.name)
+			continue
+		}
+		pusher.Push("/app.js", nil)
+		readerFrom, ok := w.(io.ReaderFrom)
+		if !ok {
+			t.Errorf("%s: expected an io.ReaderFrom", test.name)
+			continue
+		}
+		readerFrom.ReadFrom(strings.NewReader("body"))
+
+		expected := "Flush Hijack Push"
+		if test.readFrom {
+			expected += " ReadFrom"
+		}
+		if calls := strings.Join(underlying.calls, " "); calls != expected {
+			t.Errorf("%s: expected calls %q, got %q", test.name, expected, calls)
+		}
+		if body := underlying.Body.String(); body != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.name, test.body, body)
+		}
+	}
+}
+
+func TestFileResponseWriterReadFromContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	underlying := &fullResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	w := &fileResponseWriter{responseWriter: newResponseWriter(underlying), req: req.WithContext(ctx)}
+	if _, err := w.ReadFrom(strings.NewReader("body")); err != context.Canceled || w.err != context.Canceled {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+	if len(underlying.calls) != 0 || underlying.Body.Len() != 0 {
+		t.Errorf("expected nothing written, got calls %q and body %q", underlying.calls, underlying.Body)
+	}
+}
+
+func TestExposeInterfaces(t *testing.T) {
+	full := &fullResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	tests := []struct {
+		w        http.ResponseWriter
+		flusher  bool
+		hijacker bool
+		pusher   bool
+	}{
+		{full, true, true, true},
+		{nonFlusher{full}, false, false, false},
+		{struct {
+			http.ResponseWriter
+			http.Flusher
+		}{full, full}, true, false, false},
+		{struct {
+			http.ResponseWriter
+			http.Hijacker
+			http.Pusher
+		}{full, full, full}, false, true, true},
+		// the nested writers of the router are unwrapped.
+		{newResponseWriter(nonFlusher{full}), false, false, false},
+		{exposeInterfaces(newResponseWriter(struct {
+			http.ResponseWriter
+			http.Pusher
+		}{full, full})), false, false, true},
+	}
+	for i, test := range tests {
+		w := exposeInterfaces(&headResponseWriter{newResponseWriter(test.w)})
+		_, flusher := w.(http.Flusher)
+		_, hijacker := w.(http.Hijacker)
+		_, pusher := w.(http.Pusher)
+		if flusher != test.flusher || hijacker != test.hijacker || pusher != test.pusher {
+			t.Errorf("%d: expected Flusher=%t Hijacker=%t Pusher=%t, got %t %t %t", i, test.flusher, test.hijacker, test.pusher, flusher, hijacker, pusher)
+		}
+		if _, ok := w.(io.ReaderFrom); !ok {
+			t.Errorf("%d: expected an io.ReaderFrom", i)
+		}
+		if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || u.Unwrap() != test.w {
+			t.Errorf("%d: expected the writer to unwrap to the underlying writer", i)
+		}
+	}
+
+	// the router only exposes the interfaces of the underlying writer.
+	router := NewRouter()
+	router.Use(Logger(LoggerOptions{Output: ioutil.Discard}))
+	router.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := w.(http.Flusher); ok {
+			t.Error("expected no http.Flusher")
+		}
+	})
+	router.ServeHTTP(nonFlusher{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/", nil))
+}
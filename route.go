@@ -5,12 +5,14 @@
 package clevergo
 
 import (
-	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
+	"unicode"
 )
 
 var routeParamRegexp = regexp.MustCompile(`([\:|\*])([^\:\*\/]+)`)
@@ -22,6 +24,23 @@ type Route struct {
 	pattern string
 	params  []routeParam
 	handler http.Handler
+	group   *RouteGroup
+	host    string
+	accept  string
+
+	deprecated bool
+	sunset     time.Time
+
+	maxBodySize int64
+
+	templateName string
+
+	meta map[string]interface{}
+
+	middlewares []Middleware
+	fallbacks   []http.Handler
+	constraints map[string]*regexp.Regexp
+	segments    []compoundSegment
 }
 
 func newRoute(path string, handler http.Handler, opts ...RouteOption) *Route {
@@ -34,80 +53,574 @@ func newRoute(path string, handler http.Handler, opts ...RouteOption) *Route {
 		opt(r)
 	}
 	r.parse()
+	for name := range r.constraints {
+		if !r.hasParam(name) {
+			panic("route constraint for unknown parameter '" + name + "' in path '" + path + "'")
+		}
+	}
 	return r
 }
 
-func (r *Route) parse() {
-	matchs := routeParamRegexp.FindAllStringSubmatch(r.path, -1)
-	if len(matchs) == 0 {
-		return
+func (r *Route) hasParam(name string) bool {
+	for _, param := range r.params {
+		if param.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchConstraints reports whether the given params satisfy the route constraints.
+func (r *Route) matchConstraints(ps Params) bool {
+	for name, pattern := range r.constraints {
+		if !pattern.MatchString(ps.Get(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Name returns the name of the route, it is empty if the route is unnamed.
+func (r *Route) Name() string {
+	return r.name
+}
+
+// Pattern returns the registered path of the route, such as "/users/:id".
+func (r *Route) Pattern() string {
+	return r.path
+}
+
+// Handler returns the handler of the route, the middlewares are excluded.
+func (r *Route) Handler() http.Handler {
+	return r.handler
+}
+
+// SetHandler replaces the handler of the route, the middlewares of the route
+// are still applied. It is not safe to call it while the router is serving.
+func (r *Route) SetHandler(handler http.Handler) {
+	if handler == nil {
+		panic("handler must not be nil")
+	}
+	r.handler = handler
+}
+
+// chain returns the route handler wrapped with the route middlewares
+// and the middlewares of the route groups it belongs to.
+func (r *Route) chain() http.Handler {
+	return r.wrap(r.target())
+}
+
+// wrap wraps the given handler with the middlewares of the route groups it
+// belongs to, from the innermost group to the outermost one, and then with
+// the route middlewares, so that the route middlewares are invoked first.
+func (r *Route) wrap(handler http.Handler) http.Handler {
+	for g := r.group; g != nil; g = g.parent {
+		handler = Chain(handler, g.middlewares...)
+	}
+	return Chain(handler, r.middlewares...)
+}
+
+// hasMiddlewares reports whether the route or its groups have middlewares.
+func (r *Route) hasMiddlewares() bool {
+	if len(r.middlewares) > 0 {
+		return true
 	}
+	for g := r.group; g != nil; g = g.parent {
+		if len(g.middlewares) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// target returns the route handler, which tries the fallbacks in order if
+// the handler passes the request, see Pass.
+func (r *Route) target() http.Handler {
+	if len(r.fallbacks) == 0 {
+		return r.handler
+	}
+	handlers := append([]http.Handler{r.handler}, r.fallbacks...)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c := GetContext(req)
+		for i, handler := range handlers {
+			handler.ServeHTTP(w, req)
+			// the pass signal of the last handler is left to the router.
+			if c == nil || i == len(handlers)-1 || !c.takePassed() {
+				return
+			}
+		}
+	})
+}
 
-	for _, match := range matchs {
+func (r *Route) parse() {
+	segments := strings.Split(r.path, "/")
+	for i, segment := range segments {
+		if countWildcards(segment, ':') > 1 {
+			segments[i] = r.parseCompound(segment)
+			continue
+		}
+		start := indexWildcard(segment, ':')
+		if start < 0 {
+			start = indexWildcard(segment, '*')
+		}
+		if start < 0 {
+			segments[i] = unescapeWildcards(segment)
+			continue
+		}
+		match := routeParamRegexp.FindStringSubmatch(segment[start:])
+		if match == nil {
+			continue
+		}
+		name := match[2]
+		optional := strings.HasSuffix(name, "?")
+		if optional {
+			name = name[:len(name)-1]
+		}
 		r.params = append(r.params, routeParam{
-			name:     match[2],
-			required: match[1] == ":",
+			name:     name,
+			required: match[1] == ":" && !optional,
+			optional: optional,
+			catchAll: match[1] == "*",
 		})
-		r.pattern = strings.Replace(r.pattern, match[0], "{"+match[2]+"}", 1)
+		segments[i] = unescapeWildcards(segment[:start]) + strings.Replace(segment[start:], match[0], "{"+name+"}", 1)
+	}
+	r.pattern = strings.Join(segments, "/")
+}
+
+// compoundSegment is a path segment which contains multiple named parameters
+// separated by literal delimiters, such as ":name.:ext".
+type compoundSegment struct {
+	parts []segmentPart
+}
+
+// segmentPart is either a named parameter or a literal delimiter.
+type segmentPart struct {
+	value string
+	param bool
+}
+
+// parseCompound parses a segment containing multiple named parameters and
+// returns its pattern. Parameter names consist of letters, digits and
+// underscores, any other characters are treated as delimiters.
+func (r *Route) parseCompound(segment string) string {
+	start := indexWildcard(segment, ':')
+	pattern := unescapeWildcards(segment[:start])
+	var c compoundSegment
+	for s := segment[start:]; len(s) > 0; {
+		if s[0] != ':' {
+			end := indexWildcard(s, ':')
+			if end < 0 {
+				end = len(s)
+			}
+			if indexWildcard(s[:end], '*') >= 0 || strings.IndexByte(s[:end], '?') >= 0 {
+				panic("only one wildcard per path segment is allowed, has: '" + segment + "' in path '" + r.path + "'")
+			}
+			delimiter := unescapeWildcards(s[:end])
+			c.parts = append(c.parts, segmentPart{value: delimiter})
+			pattern += delimiter
+			s = s[end:]
+			continue
+		}
+
+		end := 1
+		for end < len(s) && isParamNameChar(s[end]) {
+			end++
+		}
+		name := s[1:end]
+		if name == "" {
+			panic("wildcards must be named with a non-empty name in path '" + r.path + "'")
+		}
+		if n := len(c.parts); n > 0 && c.parts[n-1].param {
+			panic("only one wildcard per path segment is allowed, has: '" + segment + "' in path '" + r.path + "'")
+		}
+		c.parts = append(c.parts, segmentPart{value: name, param: true})
+		r.params = append(r.params, routeParam{name: name, required: true})
+		pattern += "{" + name + "}"
+		s = s[end:]
 	}
+	r.segments = append(r.segments, c)
+	return pattern
 }
 
-var errWrongArgumentsNumber = errors.New("wrong number of arguments")
+func isParamNameChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// expandParams splits the values captured for compound segments into
+// individual parameters. It reports false if a value does not match its
+// segment, for example, when a delimiter is absent.
+//
+// Delimiters are searched from the right, so that the leading parameter
+// takes the longest value: "my.report.pdf" matches ":name.:ext" as
+// name="my.report" and ext="pdf".
+func (r *Route) expandParams(ps *Params) bool {
+	for _, c := range r.segments {
+		i := 0
+		for i < len(*ps) && (*ps)[i].Key != c.parts[0].value {
+			i++
+		}
+		if i == len(*ps) {
+			return false
+		}
+		value := (*ps)[i].Value
+
+		count := 0
+		for _, part := range c.parts {
+			if part.param {
+				count++
+			}
+		}
+		old := len(*ps)
+		if cap(*ps) < old+count-1 {
+			expanded := make(Params, old, old+count-1)
+			copy(expanded, *ps)
+			*ps = expanded
+		}
+		*ps = (*ps)[:old+count-1]
+		copy((*ps)[i+count:], (*ps)[i+1:old])
+
+		j := i + count - 1
+		for k := len(c.parts) - 1; k >= 0; k-- {
+			part := c.parts[k]
+			if !part.param {
+				if !strings.HasSuffix(value, part.value) {
+					return false
+				}
+				value = value[:len(value)-len(part.value)]
+				continue
+			}
+			v := value
+			if k > 0 {
+				delimiter := c.parts[k-1].value
+				idx := strings.LastIndex(value, delimiter)
+				if idx < 0 {
+					return false
+				}
+				v = value[idx+len(delimiter):]
+				value = value[:idx+len(delimiter)]
+			}
+			if v == "" {
+				return false
+			}
+			(*ps)[j] = Param{Key: part.value, Value: v}
+			j--
+		}
+	}
+	return true
+}
 
 // URL creates an url with the given arguments.
 //
 // It accepts a sequence of key/value pairs for the route variables,
-// otherwise errWrongArgumentsNumber will be returned.
+// an error is returned if the arguments are not paired, or they do not
+// match the route parameters, see URLValues.
+// The optional parameters may be omitted, the shortest URL is generated in
+// that case, for example, the route "/files/:name?" generates "/files" by
+// URL(), and "/files/report" by URL("name", "report").
 func (r *Route) URL(args ...string) (*url.URL, error) {
 	if len(args)%2 != 0 {
-		return nil, errWrongArgumentsNumber
+		return nil, fmt.Errorf("route %q expects key/value pairs of params (%s) but got %d arguments", r.name, r.paramNames(), len(args))
 	}
 
-	path := r.pattern
-	var value string
-	for _, param := range r.params {
-		value = ""
-		for i := 0; i < len(args)-1; i++ {
-			if args[i] == param.name {
-				value = args[i+1]
-				break
-			}
+	values := make(map[string]string, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		if _, ok := values[args[i]]; !ok {
+			values[args[i]] = args[i+1]
 		}
+	}
+	return r.URLValues(values)
+}
+
+// URLValues creates an url with the given parameter values, which are mapped
+// by parameter names. An error is returned if a required parameter is missing,
+// or if a value is given for an unknown parameter. The optional parameters
+// with empty values are omitted along with their leading slashes.
+func (r *Route) URLValues(values map[string]string) (*url.URL, error) {
+	for name := range values {
+		if !r.hasParam(name) {
+			return nil, fmt.Errorf("route %q has no param %q, expects params (%s)", r.name, name, r.paramNames())
+		}
+	}
+
+	path, rawPath := r.pattern, r.pattern
+	for _, param := range r.params {
+		value := values[param.name]
 		if param.required && value == "" {
-			return nil, fmt.Errorf("route %q parameter %q is required", r.name, param.name)
+			return nil, fmt.Errorf("route %q expects %d params (%s) but got %d, missing %q", r.name, len(r.params), r.paramNames(), len(values), param.name)
 		}
 
+		if param.optional && value == "" {
+			path = strings.Replace(path, "/{"+param.name+"}", "", 1)
+			rawPath = strings.Replace(rawPath, "/{"+param.name+"}", "", 1)
+			if path == "" {
+				path, rawPath = "/", "/"
+			}
+			continue
+		}
 		path = strings.Replace(path, "{"+param.name+"}", value, 1)
+		rawPath = strings.Replace(rawPath, "{"+param.name+"}", escapeParamValue(value, param.catchAll), 1)
 	}
 
-	return &url.URL{
+	// the static segments of the pattern, such as "/café", are escaped as well.
+	rawPath = escapeSegments(rawPath)
+	u := &url.URL{
 		Path: path,
-	}, nil
+	}
+	if rawPath != u.EscapedPath() {
+		u.RawPath = rawPath
+	}
+	return u, nil
+}
+
+// escapeParamValue escapes the param value as a part of URL path, the slashes
+// are escaped unless the param is a catch-all param, so that the value can be
+// restored by Router.UnescapePathValues.
+func escapeParamValue(value string, catchAll bool) string {
+	escaped := (&url.URL{Path: value}).EscapedPath()
+	if !catchAll {
+		escaped = strings.Replace(escaped, "/", "%2F", -1)
+	}
+	return escaped
+}
+
+func (r *Route) paramNames() string {
+	names := make([]string, len(r.params))
+	for i, param := range r.params {
+		names[i] = param.name
+	}
+	return strings.Join(names, ", ")
 }
 
 type routeParam struct {
 	name     string
 	required bool
+	optional bool
+	catchAll bool
 }
 
 // RouteOption applies options to a route,
 // see RouteName and RouteMiddleware.
 type RouteOption func(*Route)
 
-// RouteName is a route option for naming a route.
+// RouteName is a route option for naming a route, the name must be unique,
+// it is used for generating URL by Router.URL, and would be prefixed by the
+// path of route group, e.g. "user" becomes "/api/user" in "/api" group.
+// It panics if the name is empty or contains whitespace characters.
 func RouteName(name string) RouteOption {
+	if name == "" {
+		panic("route name must not be empty")
+	}
+	if strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		panic("route name must not contain whitespace characters in name '" + name + "'")
+	}
 	return func(r *Route) {
 		r.name = name
 	}
 }
 
-// RouteMiddleware is a route option for chainging middlewares to a route.
+// RouteHost is a route option for constraining the host of a route,
+// see Router.Host for the syntax of host pattern.
+func RouteHost(pattern string) RouteOption {
+	pattern = strings.ToLower(pattern)
+	return func(r *Route) {
+		r.host = pattern
+	}
+}
+
+// RouteConstraint is a route option for constraining the value of the given
+// parameter, the whole value must match the pattern, otherwise the route is
+// treated as not found.
+func RouteConstraint(param string, pattern *regexp.Regexp) RouteOption {
+	// anchors the pattern to match the whole value.
+	pattern = regexp.MustCompile(`^(?:` + pattern.String() + `)$`)
+	return func(r *Route) {
+		if r.constraints == nil {
+			r.constraints = make(map[string]*regexp.Regexp)
+		}
+		r.constraints[param] = pattern
+	}
+}
+
+// RouteAccept is a route option for registering multiple routes of the same
+// method and path differentiated by media types, such as "application/json"
+// and "text/html", the route is chosen by negotiating the Accept header of the
+// request. The first registered route is chosen if the request has no Accept
+// header, and 406 Not Acceptable is replied if no media type is acceptable.
+// All routes of the path must have a media type.
+func RouteAccept(mediaType string) RouteOption {
+	mediaType = strings.ToLower(mediaType)
+	return func(r *Route) {
+		r.accept = mediaType
+	}
+}
+
+// MediaType returns the media type of the route given by RouteAccept.
+func (r *Route) MediaType() string {
+	return r.accept
+}
+
+// RouteDeprecated is a route option for marking a route deprecated, the
+// responses of the route have the "Deprecation: true" header and the "Sunset"
+// header of the given time (RFC 8594), the latter is omitted if the time is
+// zero. See Router.OnDeprecated for logging the usage of deprecated routes.
+func RouteDeprecated(sunset time.Time) RouteOption {
+	return func(r *Route) {
+		r.deprecated = true
+		r.sunset = sunset
+	}
+}
+
+// Deprecated reports whether the route is deprecated, see RouteDeprecated.
+func (r *Route) Deprecated() bool {
+	return r.deprecated
+}
+
+// RouteMaxBodySize is a route option for limiting the size of request body
+// by http.MaxBytesReader, which overrides Router.MaxBodySize, the reading of
+// a larger body fails with an error. A negative size disables the limit.
+func RouteMaxBodySize(n int64) RouteOption {
+	return func(r *Route) {
+		r.maxBodySize = n
+	}
+}
+
+// RouteTemplateName is a route option for associating a template name with a
+// route, so that a shared render step can read it by RouteTemplate instead of
+// naming the template in each handler.
+func RouteTemplateName(name string) RouteOption {
+	return func(r *Route) {
+		r.templateName = name
+	}
+}
+
+// TemplateName returns the template name of the route given by
+// RouteTemplateName.
+func (r *Route) TemplateName() string {
+	return r.templateName
+}
+
+// RouteMeta is a route option for attaching arbitrary metadata to a route,
+// such as RouteMeta("scope", "admin"), which can be read by Route.Meta in
+// middlewares, for example, GetRoute(req).Meta("scope"). A later value of the
+// same key overrides the earlier one.
+func RouteMeta(key string, value interface{}) RouteOption {
+	return func(r *Route) {
+		if r.meta == nil {
+			r.meta = make(map[string]interface{})
+		}
+		r.meta[key] = value
+	}
+}
+
+// Meta returns the metadata value of the given key attached by RouteMeta, and
+// reports whether the key exists.
+func (r *Route) Meta(key string) (interface{}, bool) {
+	value, ok := r.meta[key]
+	return value, ok
+}
+
+// RouteTemplate returns the template name of the matched route of the request,
+// the route name is returned if the route has no template name. It requires
+// Router.SaveMatchedRoute to be turned on, an empty string is returned if the
+// route is unknown.
+func RouteTemplate(req *http.Request) string {
+	route := GetRoute(req)
+	if route == nil {
+		return ""
+	}
+	if route.templateName != "" {
+		return route.templateName
+	}
+	return route.name
+}
+
+// RouteMiddleware is a route option for chainging middlewares to a route,
+// the middlewares are invoked after the router middlewares and before the
+// middlewares of the route groups, just like wrapping the handler after the
+// groups did.
 func RouteMiddleware(middlewares ...Middleware) RouteOption {
 	return func(r *Route) {
-		r.handler = Chain(r.handler, middlewares...)
+		r.middlewares = append(r.middlewares, middlewares...)
+	}
+}
+
+// RouteFallback is a route option for registering the handlers which are tried
+// in order if the route handler passes the request by Pass, for example,
+// several handlers that compete for the same path by content negotiation.
+// The route middlewares are invoked once for all of them.
+func RouteFallback(handlers ...http.Handler) RouteOption {
+	for _, handler := range handlers {
+		if handler == nil {
+			panic("fallback handler must not be nil")
+		}
+	}
+	return func(r *Route) {
+		r.fallbacks = append(r.fallbacks, handlers...)
 	}
 }
 
+// RouteConsumes is a route option for rejecting the requests of which media
+// type of the Content-Type header is not the given media type with 415
+// Unsupported Media Type before invoking the handler, the parameters, such as
+// charset, are ignored. The requests without body and Content-Type header are
+// accepted.
+func RouteConsumes(mediaType string) RouteOption {
+	mediaType = strings.ToLower(mediaType)
+	return RouteMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctype := req.Header.Get("Content-Type")
+			if ctype != "" || req.ContentLength != 0 {
+				if t, _, err := mime.ParseMediaType(ctype); err != nil || t != mediaType {
+					http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+					return
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+}
+
+// RouteRequireQuery is a route option for rejecting the requests which miss
+// any of the given query parameters with 400 Bad Request before invoking the
+// handler, the missing keys are listed in the response body. A parameter
+// with an empty value, such as "q" of "/search?q=", is not missing.
+func RouteRequireQuery(keys ...string) RouteOption {
+	return RouteMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			query := req.URL.Query()
+			var missing []string
+			for _, key := range keys {
+				if _, ok := query[key]; !ok {
+					missing = append(missing, key)
+				}
+			}
+			if len(missing) > 0 {
+				http.Error(w, "missing query parameters: "+strings.Join(missing, ", "), http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+}
+
+// RoutePush is a route option for pushing the given asset paths by HTTP/2
+// server push before invoking the handler, it does nothing if the connection
+// does not support server push, such as HTTP/1. Push errors are ignored.
+func RoutePush(paths ...string) RouteOption {
+	return RouteMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if pusher, ok := w.(http.Pusher); ok {
+				for _, path := range paths {
+					if err := pusher.Push(path, nil); err == http.ErrNotSupported {
+						break
+					}
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+}
+
 // RouteGroupOption applies options to a route group.
 type RouteGroupOption func(*RouteGroup)
 
@@ -118,15 +631,58 @@ func RouteGroupMiddleware(middlewares ...Middleware) RouteGroupOption {
 	}
 }
 
+// RouteGroupName is a option for prefixing the names of the routes of a route
+// group, for example, the route named "users" of a group with prefix "admin."
+// is named "admin.users". The prefixes of nested groups are concatenated, and
+// the group path is no longer used as the name prefix.
+func RouteGroupName(prefix string) RouteGroupOption {
+	return func(r *RouteGroup) {
+		r.namePrefix = prefix
+		r.hasNamePrefix = true
+	}
+}
+
+// RouteGroupDefaults is a option for applying the given route options to all
+// routes of a route group and its nested groups, the options of parent groups
+// are applied first, and the options passed to the registering functions are
+// applied last, so that they override the defaults, for example, the route
+// constraint of the same parameter. Default constraints are ignored for the
+// routes without the parameter.
+func RouteGroupDefaults(opts ...RouteOption) RouteGroupOption {
+	return func(r *RouteGroup) {
+		r.defaults = append(r.defaults, opts...)
+	}
+}
+
+// RouteGroupNotFound is a option for setting the NotFound handler of a route
+// group, which is called instead of Router.NotFound if no route matches a path
+// under the group path, for example, a JSON 404 response for "/api". The group
+// with the longest matching path wins, and the handler of a host group only
+// applies to the requests of that host.
+func RouteGroupNotFound(handler http.Handler) RouteGroupOption {
+	return func(r *RouteGroup) {
+		r.notFound = handler
+	}
+}
+
 // RouteGroup implements an nested route group,
 // see https://github.com/julienschmidt/httprouter/pull/89.
 type RouteGroup struct {
-	parent      *Router
+	router      *Router
+	parent      *RouteGroup
+	host        string
 	path        string
 	middlewares []Middleware
+
+	namePrefix    string
+	hasNamePrefix bool
+
+	defaults []RouteOption
+
+	notFound http.Handler
 }
 
-func newRouteGroup(parent *Router, path string, opts ...RouteGroupOption) *RouteGroup {
+func newRouteGroup(router *Router, path string, opts ...RouteGroupOption) *RouteGroup {
 	if path[0] != '/' {
 		panic("path must begin with '/' in path '" + path + "'")
 	}
@@ -136,7 +692,7 @@ func newRouteGroup(parent *Router, path string, opts ...RouteGroupOption) *Route
 		path = path[:len(path)-1]
 	}
 
-	route := &RouteGroup{parent: parent, path: path}
+	route := &RouteGroup{router: router, path: path}
 	for _, opt := range opts {
 		opt(route)
 	}
@@ -146,7 +702,21 @@ func newRouteGroup(parent *Router, path string, opts ...RouteGroupOption) *Route
 
 // Group creates route group with the given path and optional route options.
 func (r *RouteGroup) Group(path string, opts ...RouteGroupOption) *RouteGroup {
-	return newRouteGroup(r.parent, r.subPath(path), opts...)
+	group := newRouteGroup(r.router, r.subPath(path), opts...)
+	group.parent = r
+	group.host = r.host
+	if r.hasNamePrefix {
+		group.namePrefix = r.namePrefix + group.namePrefix
+		group.hasNamePrefix = true
+	}
+	r.router.addGroupNotFound(group)
+	return group
+}
+
+// Use registers middlewares which will be applied to the routes of this group
+// and its nested groups, the middlewares of parent group are invoked first.
+func (r *RouteGroup) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
 }
 
 // HandleFunc is a shortcut of RouteGroup.Handle(http.MethodDelete, path, http.HandlerFunc(handle), opts ...)
@@ -156,14 +726,33 @@ func (r *RouteGroup) HandleFunc(method, path string, handle http.HandlerFunc, op
 
 // Handle registers a new request handler with the given path, method and optional route options.
 func (r *RouteGroup) Handle(method, path string, handler http.Handler, opts ...RouteOption) {
-	handler = Chain(handler, r.middlewares...)
-
+	opts = append([]RouteOption{r.applyDefaults}, opts...)
 	opts = append(opts, func(route *Route) {
+		route.group = r
+		if r.host != "" {
+			route.host = r.host
+		}
 		if route.name != "" {
-			route.name = r.path + "/" + route.name
+			route.name = r.routeName(route.name)
 		}
 	})
-	r.parent.Handle(method, r.subPath(path), handler, opts...)
+	r.router.Handle(method, r.subPath(path), handler, opts...)
+}
+
+// Any registers the handle for GET, HEAD, OPTIONS, POST, PUT, PATCH and DELETE methods,
+// see Router.Any.
+func (r *RouteGroup) Any(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.Match(anyMethods, path, handle, opts...)
+}
+
+// Match registers the handle for the given methods, see Router.Match.
+func (r *RouteGroup) Match(methods []string, path string, handle http.HandlerFunc, opts ...RouteOption) {
+	for i, method := range methods {
+		r.HandleFunc(method, path, handle, opts...)
+		if i == 0 {
+			opts = append(opts[:len(opts):len(opts)], routeNameless)
+		}
+	}
 }
 
 // Get is a shortcut of RouteGroup.HandleFunc(http.MethodGet, path, handle, opts ...)
@@ -201,6 +790,57 @@ func (r *RouteGroup) Delete(path string, handle http.HandlerFunc, opts ...RouteO
 	r.HandleFunc(http.MethodDelete, path, handle, opts...)
 }
 
+// applyDefaults applies the default route options of the group and its
+// parents, the constraints of unknown parameters are dropped.
+func (r *RouteGroup) applyDefaults(route *Route) {
+	var groups []*RouteGroup
+	for g := r; g != nil; g = g.parent {
+		groups = append(groups, g)
+	}
+	applied := false
+	for i := len(groups) - 1; i >= 0; i-- {
+		for _, opt := range groups[i].defaults {
+			opt(route)
+			applied = true
+		}
+	}
+	if !applied || len(route.constraints) == 0 {
+		return
+	}
+
+	parsed := &Route{path: route.path, pattern: route.path}
+	parsed.parse()
+	for name := range route.constraints {
+		if !parsed.hasParam(name) {
+			delete(route.constraints, name)
+		}
+	}
+}
+
+// routeName returns the name of the group route, it is prefixed by the name
+// prefix if present, otherwise by the group path.
+func (r *RouteGroup) routeName(name string) string {
+	if r.hasNamePrefix {
+		return r.namePrefix + name
+	}
+	if r.path != "" && r.path != "/" {
+		return r.path + "/" + name
+	}
+	return name
+}
+
+// subPath joins the group path and the given path with a single slash, so
+// that the paths of root group "/" do not have a duplicate slash, and "v1" is
+// joined as "/v1". An empty path refers to the group path itself.
 func (r *RouteGroup) subPath(path string) string {
+	if path == "" {
+		return r.path
+	}
+	if path[0] != '/' {
+		path = "/" + path
+	}
+	if r.path == "/" {
+		return path
+	}
 	return r.path + path
 }
@@ -6,10 +6,15 @@ package clevergo
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRouteGroupURL(t *testing.T) {
@@ -66,6 +71,79 @@ func TestRouteGroupURL(t *testing.T) {
 
 }
 
+func TestRouteURLErrors(t *testing.T) {
+	route := newRoute("/posts/:id/:slug", nil, RouteName("post"))
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"id"}, `route "post" expects key/value pairs of params (id, slug) but got 1 arguments`},
+		{[]string{"id", "1"}, `route "post" expects 2 params (id, slug) but got 1, missing "slug"`},
+		{[]string{"id", "1", "title", "foo"}, `route "post" has no param "title", expects params (id, slug)`},
+	}
+	for _, test := range tests {
+		_, err := route.URL(test.args...)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+	}
+}
+
+func TestRouteURLOptionalParam(t *testing.T) {
+	tests := []struct {
+		path     string
+		args     []string
+		expected string
+		err      string
+	}{
+		{"/files/:name?", nil, "/files", ""},
+		{"/files/:name?", []string{"name", "report"}, "/files/report", ""},
+		{"/files/:name?", []string{"name", ""}, "/files", ""},
+		{"/:lang?", nil, "/", ""},
+		{"/:lang?", []string{"lang", "en"}, "/en", ""},
+		{"/users/:id/files/:name?", []string{"id", "1"}, "/users/1/files", ""},
+		{"/users/:id/files/:name?", []string{"id", "1", "name", "report"}, "/users/1/files/report", ""},
+		{"/users/:id/files/:name?", []string{"name", "report"}, "", `route "files" expects 2 params (id, name) but got 1, missing "id"`},
+		{"/files/:name?", []string{"name"}, "", `route "files" expects key/value pairs of params (name) but got 1 arguments`},
+	}
+	for _, test := range tests {
+		route := newRoute(test.path, nil, RouteName("files"))
+		url, err := route.URL(test.args...)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s %v: expected error %q, got %v", test.path, test.args, test.err, err)
+			}
+			continue
+		}
+		if err != nil || url.String() != test.expected {
+			t.Errorf("%s %v: expected url %q, got %q, %v", test.path, test.args, test.expected, url, err)
+		}
+	}
+}
+
+func TestRouteURLValues(t *testing.T) {
+	route := newRoute("/posts/:year/:month/:title", nil, RouteName("post"))
+	url, err := route.URLValues(map[string]string{"title": "foo", "year": "2020", "month": "01"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if url.String() != "/posts/2020/01/foo" {
+		t.Errorf("expected url %q, got %q", "/posts/2020/01/foo", url)
+	}
+
+	if _, err = route.URLValues(map[string]string{"year": "2020"}); err == nil {
+		t.Error("expected an error, got nil")
+	}
+	if _, err = route.URLValues(map[string]string{"year": "2020", "month": "01", "title": "foo", "page": "1"}); err == nil {
+		t.Error("expected an error, got nil")
+	}
+
+	route = newRoute("/files/:name?", nil)
+	if url, err = route.URLValues(nil); err != nil || url.String() != "/files" {
+		t.Errorf("expected url %q, got %q, %v", "/files", url, err)
+	}
+}
+
 func TestRouteGroupAPI(t *testing.T) {
 	var get, head, options, post, put, patch, delete, handler, handlerFunc bool
 
@@ -156,6 +234,34 @@ func TestRouteGroupAPI(t *testing.T) {
 	}
 }
 
+func TestRouteName(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/", echoHandler(""), RouteName("home"))
+	router.Handle(http.MethodGet, "/about", echoHandler(""))
+	router.Group("/api").Handle(http.MethodGet, "/users", echoHandler(""), RouteName("users"))
+
+	tests := map[string]string{
+		"/":          "home",
+		"/about":     "",
+		"/api/users": "/api/users",
+	}
+	for path, name := range tests {
+		route, _, _ := router.Lookup(http.MethodGet, path)
+		if route.Name() != name {
+			t.Errorf("expected name %q, got %q", name, route.Name())
+		}
+	}
+}
+
+func TestRoutePattern(t *testing.T) {
+	for _, path := range []string{"/", "/users/:id", "/files/:name?", "/static/*filepath"} {
+		route := newRoute(path, echoHandler(""))
+		if pattern := route.Pattern(); pattern != path {
+			t.Errorf("expected pattern %q, got %q", path, pattern)
+		}
+	}
+}
+
 func TestRouteMiddleware(t *testing.T) {
 	m1 := echoMiddleware("m1")
 	m2 := echoMiddleware("m2")
@@ -178,6 +284,14 @@ func TestRouteMiddleware(t *testing.T) {
 	if w.Body.String() != "m1 m2 hello" {
 		t.Errorf("expected body %q, got %q", "m1 m2 hello", w.Body)
 	}
+
+	// router middlewares are invoked before route middlewares.
+	router.Use(echoMiddleware("global"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "global m1 m2 hello" {
+		t.Errorf("expected body %q, got %q", "global m1 m2 hello", w.Body)
+	}
 }
 
 func TestNestedRouteGroup(t *testing.T) {
@@ -224,6 +338,453 @@ func TestNestedRouteGroup(t *testing.T) {
 	}
 }
 
+func TestRouteGroupUse(t *testing.T) {
+	handler := echoHandler("hello")
+
+	router := NewRouter()
+	router.Use(echoMiddleware("global"))
+	admin := router.Group("/admin", RouteGroupMiddleware(echoMiddleware("m1")))
+	admin.Handle(http.MethodGet, "/", handler)
+	admin.Use(echoMiddleware("m2"))
+	users := admin.Group("/users", RouteGroupMiddleware(echoMiddleware("m3")))
+	users.Handle(http.MethodGet, "/", handler, RouteMiddleware(echoMiddleware("route")))
+	users.Use(echoMiddleware("m4"))
+	router.Handle(http.MethodGet, "/", handler)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/", "global hello"},
+		{"/admin/", "global m1 m2 hello"},
+		// the route middlewares are invoked before the group middlewares.
+		{"/admin/users/", "global route m1 m2 m3 m4 hello"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Errorf("expected body %q, got %q", test.body, w.Body)
+		}
+	}
+}
+
+func TestRouteGroupNested(t *testing.T) {
+	router := NewRouter()
+	tests := []struct {
+		group    *RouteGroup
+		path     string
+		expected string
+	}{
+		{router.Group("/api").Group("/v1"), "/users", "/api/v1/users"},
+		{router.Group("/api/").Group("/v2/"), "/users", "/api/v2/users"},
+		{router.Group("/").Group("/v3"), "/users", "/v3/users"},
+		{router.Group("/"), "/about", "/about"},
+		{router.Group("/admin").Group("/"), "/login", "/admin/login"},
+	}
+	for _, test := range tests {
+		test.group.Handle(http.MethodGet, test.path, echoHandler(test.expected), RouteName(test.expected))
+		if !router.HasRoute(http.MethodGet, test.expected) {
+			t.Errorf("expected route %q, got %v", test.expected, router.Routes())
+		}
+	}
+	if _, ok := router.NamedRoute("/about"); !ok {
+		t.Errorf("expected route name %q, got %v", "/about", router.NamedRoutes())
+	}
+
+	api := router.Group("/api")
+	api.Get("", echoHandler("api").ServeHTTP)
+	api.Group("v4").Get("users", echoHandler("v4").ServeHTTP)
+	router.Group("/").Get("", echoHandler("home").ServeHTTP)
+	for _, path := range []string{"/api", "/api/v4/users", "/"} {
+		if !router.HasRoute(http.MethodGet, path) {
+			t.Errorf("expected route %q, got %v", path, router.Routes())
+		}
+	}
+}
+
+func TestRouteGroupName(t *testing.T) {
+	router := NewRouter()
+	admin := router.Group("/admin", RouteGroupName("admin."))
+	admin.Get("/users", func(http.ResponseWriter, *http.Request) {}, RouteName("users"))
+	admin.Group("/posts", RouteGroupName("posts.")).Get("/:id", func(http.ResponseWriter, *http.Request) {}, RouteName("show"))
+	admin.Group("/settings").Get("/", func(http.ResponseWriter, *http.Request) {}, RouteName("settings"))
+	router.Group("/api").Group("/v1", RouteGroupName("v1.")).Get("/users", func(http.ResponseWriter, *http.Request) {}, RouteName("users"))
+	router.Host("api.example.com", RouteGroupName("api.")).Get("/ping", func(http.ResponseWriter, *http.Request) {}, RouteName("ping"))
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"admin.users", nil, "/admin/users"},
+		{"admin.posts.show", []string{"id", "1"}, "/admin/posts/1"},
+		{"admin.settings", nil, "/admin/settings/"},
+		{"v1.users", nil, "/api/v1/users"},
+		{"api.ping", nil, "/ping"},
+	}
+	for _, test := range tests {
+		url, err := router.URL(test.name, test.args...)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			continue
+		}
+		if url.String() != test.expected {
+			t.Errorf("expected url %q, got %q", test.expected, url)
+		}
+	}
+}
+
+func TestRouteGroupDefaults(t *testing.T) {
+	router := NewRouter()
+	users := router.Group("/users", RouteGroupDefaults(
+		RouteConstraint("id", regexp.MustCompile(`\d+`)),
+		RouteMiddleware(echoMiddleware("m1")),
+	))
+	users.Handle(http.MethodGet, "/", echoHandler("list"))
+	users.Handle(http.MethodGet, "/:id", echoHandler("show"))
+	users.Handle(http.MethodGet, "/:id/edit", echoHandler("edit"), RouteMiddleware(echoMiddleware("m2")))
+	posts := users.Group("/:id/posts", RouteGroupDefaults(RouteMiddleware(echoMiddleware("m3"))))
+	posts.Handle(http.MethodGet, "/:slug", echoHandler("post"), RouteConstraint("id", regexp.MustCompile(`[a-z]+`)))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/", http.StatusOK, "m1 list"},
+		{"/users/1", http.StatusOK, "m1 show"},
+		{"/users/foo", http.StatusNotFound, ""},
+		{"/users/1/edit", http.StatusOK, "m1 m2 edit"},
+		{"/users/foo/posts/hello", http.StatusOK, "m1 m3 post"},
+		{"/users/1/posts/hello", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected code %d, got %d", test.path, test.code, w.Code)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.path, test.body, w.Body)
+		}
+	}
+}
+
+func TestRouteGroupNotFound(t *testing.T) {
+	router := NewRouter()
+	router.NotFound = echoHandler("site")
+	api := router.Group("/api", RouteGroupNotFound(echoHandler("api")))
+	api.Get("/users", func(http.ResponseWriter, *http.Request) {})
+	api.Group("/v2", RouteGroupNotFound(echoHandler("v2")))
+	api.Group("/v1")
+	router.Host("admin.example.com", RouteGroupNotFound(echoHandler("admin"))).Get("/", func(http.ResponseWriter, *http.Request) {})
+
+	tests := []struct {
+		host string
+		path string
+		body string
+	}{
+		{"example.com", "/foo", "site"},
+		{"example.com", "/apis", "site"},
+		{"example.com", "/api", "api"},
+		{"example.com", "/api/foo", "api"},
+		{"example.com", "/api/v1/foo", "api"},
+		{"example.com", "/api/v2", "v2"},
+		{"example.com", "/api/v2/foo", "v2"},
+		{"admin.example.com", "/foo", "admin"},
+		{"admin.example.com", "/api/foo", "admin"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://"+test.host+test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Errorf("%s%s: expected body %q, got %q", test.host, test.path, test.body, w.Body)
+		}
+	}
+}
+
+func TestRouteFallback(t *testing.T) {
+	accepts := func(ctype, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Accept") != ctype {
+				Pass(req)
+				return
+			}
+			fmt.Fprint(w, body)
+		}
+	}
+	router := NewRouter()
+	router.NotFound = echoHandler("not found")
+	router.Get("/users", accepts("application/json", "json"), RouteFallback(
+		accepts("text/html", "html"),
+		accepts("text/plain", "text"),
+	), RouteMiddleware(echoMiddleware("m")))
+	router.Get("/*path", accepts("text/csv", "csv"))
+	router.Handle(MethodAny, "/users", echoHandler("any"))
+
+	tests := []struct {
+		method string
+		accept string
+		body   string
+	}{
+		{http.MethodGet, "application/json", "m json"},
+		{http.MethodGet, "text/html", "m html"},
+		{http.MethodGet, "text/plain", "m text"},
+		{http.MethodGet, "text/csv", "m csv"},
+		{http.MethodGet, "image/png", "m any"},
+		{http.MethodPost, "text/plain", "any"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, "/users", nil)
+		req.Header.Set("Accept", test.accept)
+		router.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Errorf("%s %s: expected body %q, got %q", test.method, test.accept, test.body, w.Body)
+		}
+	}
+
+	router.RemoveRoute(MethodAny, "/users")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if w.Body.String() != "m not found" {
+		t.Errorf("expected body %q, got %q", "m not found", w.Body)
+	}
+}
+
+func TestRouteDeprecated(t *testing.T) {
+	sunset := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var deprecated []string
+	router := NewRouter()
+	router.OnDeprecated = func(route *Route, req *http.Request) {
+		deprecated = append(deprecated, route.Pattern())
+	}
+	router.Get("/v1/users", func(http.ResponseWriter, *http.Request) {}, RouteDeprecated(sunset))
+	router.Get("/v1/posts", func(http.ResponseWriter, *http.Request) {}, RouteDeprecated(time.Time{}))
+	router.Get("/v2/users", func(http.ResponseWriter, *http.Request) {})
+
+	tests := []struct {
+		path        string
+		deprecation string
+		sunset      string
+	}{
+		{"/v1/users", "true", "Tue, 01 Jan 2030 00:00:00 GMT"},
+		{"/v1/posts", "true", ""},
+		{"/v2/users", "", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if v := w.Header().Get("Deprecation"); v != test.deprecation {
+			t.Errorf("%s: expected Deprecation header %q, got %q", test.path, test.deprecation, v)
+		}
+		if v := w.Header().Get("Sunset"); v != test.sunset {
+			t.Errorf("%s: expected Sunset header %q, got %q", test.path, test.sunset, v)
+		}
+	}
+	if expected := []string{"/v1/users", "/v1/posts"}; !reflect.DeepEqual(deprecated, expected) {
+		t.Errorf("expected deprecated routes %v, got %v", expected, deprecated)
+	}
+}
+
+func TestRouteMaxBodySize(t *testing.T) {
+	router := NewRouter()
+	router.MaxBodySize = 4
+	handle := func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write(body)
+	}
+	router.Post("/json", handle)
+	router.Post("/upload", handle, RouteMaxBodySize(8))
+	router.Post("/unlimited", handle, RouteMaxBodySize(-1))
+
+	tests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/json", "1234", http.StatusOK},
+		{"/json", "12345", http.StatusRequestEntityTooLarge},
+		{"/upload", "12345678", http.StatusOK},
+		{"/upload", "123456789", http.StatusRequestEntityTooLarge},
+		{"/unlimited", "123456789", http.StatusOK},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body)))
+		if w.Code != test.code {
+			t.Errorf("%s %q: expected status code %d, got %d", test.path, test.body, test.code, w.Code)
+		}
+	}
+}
+
+func TestRouteTemplate(t *testing.T) {
+	router := NewRouter()
+	router.SaveMatchedRoute = true
+	handle := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, RouteTemplate(req))
+	}
+	router.Get("/", handle, RouteName("home"), RouteTemplateName("index.html"))
+	router.Get("/about", handle, RouteName("about"))
+	router.Get("/contact", handle)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/", "index.html"},
+		{"/about", "about"},
+		{"/contact", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Body.String() != test.body {
+			t.Errorf("%s: expected template %q, got %q", test.path, test.body, w.Body)
+		}
+	}
+
+	if route, _ := router.NamedRoute("home"); route.TemplateName() != "index.html" {
+		t.Errorf("expected template name %q, got %q", "index.html", route.TemplateName())
+	}
+	if name := RouteTemplate(httptest.NewRequest(http.MethodGet, "/", nil)); name != "" {
+		t.Errorf("expected empty template name, got %q", name)
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	router := NewRouter()
+	router.SaveMatchedRoute = true
+	requireScope := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if scope, ok := GetRoute(req).Meta("scope"); ok && scope != req.Header.Get("X-Scope") {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+	router.Use(requireScope)
+	router.Get("/admin", echoHandler("ok").ServeHTTP, RouteName("admin"), RouteMeta("auth", "required"), RouteMeta("scope", "admin"))
+	router.Get("/public", echoHandler("ok").ServeHTTP)
+
+	tests := []struct {
+		path  string
+		scope string
+		code  int
+	}{
+		{"/admin", "", http.StatusForbidden},
+		{"/admin", "user", http.StatusForbidden},
+		{"/admin", "admin", http.StatusOK},
+		{"/public", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Header.Set("X-Scope", test.scope)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s %q: expected status code %d, got %d", test.path, test.scope, test.code, w.Code)
+		}
+	}
+
+	route, _ := router.NamedRoute("admin")
+	if value, ok := route.Meta("auth"); !ok || value != "required" {
+		t.Errorf("expected meta %q, got %v, %t", "required", value, ok)
+	}
+	if value, ok := route.Meta("missing"); ok || value != nil {
+		t.Errorf("expected no meta, got %v, %t", value, ok)
+	}
+
+	sub := NewRouter()
+	sub.Get("/users", echoHandler("ok").ServeHTTP, RouteName("users"), RouteMeta("scope", "admin"), RouteMeta("scope", "staff"))
+	router.Mount("/api", sub)
+	route, _ = router.NamedRoute("/api/users")
+	if value, _ := route.Meta("scope"); value != "staff" {
+		t.Errorf("expected mounted meta %q, got %v", "staff", value)
+	}
+}
+
+func TestRouteConsumes(t *testing.T) {
+	router := NewRouter()
+	router.Post("/users", echoHandler("ok").ServeHTTP, RouteConsumes("application/json"))
+
+	tests := []struct {
+		ctype string
+		body  string
+		code  int
+	}{
+		{"application/json", "{}", http.StatusOK},
+		{"application/json; charset=utf-8", "{}", http.StatusOK},
+		{"Application/JSON", "{}", http.StatusOK},
+		{"text/plain", "{}", http.StatusUnsupportedMediaType},
+		{"", "{}", http.StatusUnsupportedMediaType},
+		{"application/json; charset", "{}", http.StatusUnsupportedMediaType},
+		{"", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(test.body))
+		if test.ctype != "" {
+			req.Header.Set("Content-Type", test.ctype)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%q %q: expected status code %d, got %d", test.ctype, test.body, test.code, w.Code)
+		}
+	}
+}
+
+func TestRouteRequireQuery(t *testing.T) {
+	router := NewRouter()
+	router.Get("/search", echoHandler("ok").ServeHTTP, RouteRequireQuery("q", "page"))
+
+	tests := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/search?q=go&page=1", http.StatusOK, "ok"},
+		{"/search?q=&page=", http.StatusOK, "ok"},
+		{"/search?q=go", http.StatusBadRequest, "missing query parameters: page\n"},
+		{"/search", http.StatusBadRequest, "missing query parameters: q, page\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("%s: expected %d %q, got %d %q", test.url, test.code, test.body, w.Code, w.Body)
+		}
+	}
+}
+
+func TestRoutePush(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/", echoHandler("home"), RoutePush("/app.js", "/app.css"))
+
+	w := &fullResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	router.ServeHTTP(w, req)
+	if calls := strings.Join(w.calls, " "); calls != "Push Push" || w.Body.String() != "home" {
+		t.Errorf("unexpected calls %q and body %q", calls, w.Body)
+	}
+
+	// HTTP/1
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Body.String() != "home" {
+		t.Errorf("expected body %q, got %q", "home", recorder.Body)
+	}
+}
+
 func TestNewRouteGroup(t *testing.T) {
 	tests := []struct {
 		path         string
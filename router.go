@@ -6,20 +6,27 @@ package clevergo
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
 )
 
-type contextKey int
-
-const (
-	paramsKey contextKey = iota
-	routeKey
-)
+// MethodAny is the wildcard method, its handlers serve the requests of any
+// method which is not registered for the path, see Router.Handle.
+const MethodAny = "*"
 
 // Param is a single URL parameter, consisting of a key and a value.
 type Param struct {
@@ -43,6 +50,27 @@ func (ps Params) Get(name string) string {
 	return ""
 }
 
+// GetDefault returns the value of the first Param which key matches the given
+// name, def is returned if no matching Param is found.
+func (ps Params) GetDefault(name, def string) string {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+	return def
+}
+
+// Has reports whether a Param which key matches the given name exists.
+func (ps Params) Has(name string) bool {
+	for _, p := range ps {
+		if p.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Bool returns the boolean value of the given name.
 func (ps Params) Bool(name string) (bool, error) {
 	return strconv.ParseBool(ps.Get(name))
@@ -58,40 +86,176 @@ func (ps Params) Int(name string) (int, error) {
 	return strconv.Atoi(ps.Get(name))
 }
 
+// Int32 returns the int32 value of the given name.
+func (ps Params) Int32(name string) (int32, error) {
+	i, err := strconv.ParseInt(ps.Get(name), 10, 32)
+	return int32(i), err
+}
+
 // Int64 returns the int64 value of the given name.
 func (ps Params) Int64(name string) (int64, error) {
 	return strconv.ParseInt(ps.Get(name), 10, 64)
 }
 
+// Uint returns the uint value of the given name.
+func (ps Params) Uint(name string) (uint, error) {
+	u, err := strconv.ParseUint(ps.Get(name), 10, 0)
+	return uint(u), err
+}
+
 // Uint64 returns the uint64 value of the given name.
 func (ps Params) Uint64(name string) (uint64, error) {
 	return strconv.ParseUint(ps.Get(name), 10, 64)
 }
 
+// Duration returns the time.Duration value of the given name.
+func (ps Params) Duration(name string) (time.Duration, error) {
+	return time.ParseDuration(ps.Get(name))
+}
+
+// Time returns the time.Time value of the given name, which is parsed by
+// the given layout.
+func (ps Params) Time(name, layout string) (time.Time, error) {
+	return time.Parse(layout, ps.Get(name))
+}
+
+// TimeRFC3339 is a shortcut of Params.Time(name, time.RFC3339).
+func (ps Params) TimeRFC3339(name string) (time.Time, error) {
+	return ps.Time(name, time.RFC3339)
+}
+
+// Slice splits the value of the given param by '/' and drops the empty
+// elements, it is useful for catch-all params, for example, "/a/b//c" is
+// split into ["a", "b", "c"]. An empty slice is returned for an empty or
+// missing value.
+// Note that an escaped slash "%2F" does not split the value only if the value
+// is kept escaped, that is, Router.UseRawPath is enabled without
+// Router.UnescapePathValues. Otherwise the value is unescaped, and the escaped
+// slashes can not be told apart from the path separators, which is a
+// limitation of Slice.
+func (ps Params) Slice(name string) []string {
+	value := ps.Get(name)
+	elems := make([]string, 0, strings.Count(value, "/")+1)
+	for _, elem := range strings.Split(value, "/") {
+		if elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
+
+// Map returns the params as a map keyed by param names, the first value wins
+// if multiple params have the same name, just like Get. A nil map is returned
+// for empty params.
+func (ps Params) Map() map[string]string {
+	if len(ps) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(ps))
+	for _, p := range ps {
+		if _, ok := m[p.Key]; !ok {
+			m[p.Key] = p.Value
+		}
+	}
+	return m
+}
+
+// Each calls fn for each param in order, it stops and returns the error
+// returned by fn, if any.
+func (ps Params) Each(fn func(key, value string) error) error {
+	for _, p := range ps {
+		if err := fn(p.Key, p.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetParams returns params of the request.
 func GetParams(req *http.Request) Params {
-	ps, _ := req.Context().Value(paramsKey).(Params)
-	return ps
+	if c := GetContext(req); c != nil {
+		return c.Params
+	}
+	return nil
+}
+
+// WithParams returns a shallow copy of the request with the given params,
+// which can be retrieved by GetParams, it is useful for testing handlers.
+func WithParams(req *http.Request, ps Params) *http.Request {
+	return withContext(req, func(c *Context) {
+		c.Params = ps
+	})
 }
 
 // GetRoute returns matched route of the request, it
 // only works if Router.SaveMatchedRoute is turn on.
 func GetRoute(req *http.Request) *Route {
-	r, _ := req.Context().Value(routeKey).(*Route)
-	return r
+	if c := GetContext(req); c != nil {
+		return c.Route
+	}
+	return nil
+}
+
+// WithRoute returns a shallow copy of the request with the given route,
+// which can be retrieved by GetRoute, it is useful for testing handlers.
+func WithRoute(req *http.Request, route *Route) *http.Request {
+	return withContext(req, func(c *Context) {
+		c.Route = route
+	})
 }
 
 // Router is a http.Handler which can be used to dispatch requests to different
 // handler functions via configurable routes
 type Router struct {
+	// The request counters and the number of active requests, which are
+	// placed first for the 64-bit alignment required by the atomic operations
+	// on 32-bit platforms.
+	stats  routerStats
+	active int64
+
 	trees map[string]*node
 
+	// Route trees of hosts, keyed by host pattern.
+	hosts map[string]*hostRoutes
+
 	// Named routes.
 	routes map[string]*Route
 
+	// The route groups which have a NotFound handler.
+	notFoundGroups []*RouteGroup
+
+	// The routes registered for the same method and path as a route, which
+	// are negotiated by the Accept header, see RouteAccept.
+	variants map[*Route][]*Route
+
 	paramsPool sync.Pool
 	maxParams  uint16
 
+	middlewares []Middleware
+
+	// draining is set to 1 by Shutdown, idle is closed once there is no
+	// active request while draining.
+	draining int32
+	drainMu  sync.Mutex
+	idle     chan struct{}
+
+	// The maximum size of request body of the matched routes, the body is
+	// wrapped by http.MaxBytesReader before the middlewares are invoked, so
+	// that the reading of a larger body fails with an error. Zero means no
+	// limit, see RouteMaxBodySize for overriding it per route.
+	MaxBodySize int64
+
+	// If enabled, allocates the params of each request, sized to the maximum
+	// number of params, instead of reusing them by a sync.Pool. The pool
+	// saves allocations, but its round trip may cost more than allocating a
+	// few params, run BenchmarkParamsPool against the workload to choose.
+	DisableParamsPool bool
+
+	// If enabled, counts the requests by the outcomes, such as matches and
+	// 404s, which can be retrieved by Stats. It is off by default to keep
+	// the counting out of the hot path.
+	EnableStats bool
+
 	// If enabled, adds the matched route onto the http.Request context
 	// before invoking the handler.
 	SaveMatchedRoute bool
@@ -103,6 +267,12 @@ type Router struct {
 	// and 308 for all other request methods.
 	RedirectTrailingSlash bool
 
+	// If enabled, a trailing slash mismatch is a hard 404, for example, /foo/
+	// is not found if a route only exists for /foo, it takes precedence over
+	// RedirectTrailingSlash, and RedirectFixedPath does not fix the trailing
+	// slash either.
+	StrictSlash bool
+
 	// If enabled, the router tries to fix the current request path, if no
 	// handle is registered for it.
 	// First superfluous path elements like ../ or // are removed.
@@ -114,6 +284,94 @@ type Router struct {
 	// RedirectTrailingSlash is independent of this option.
 	RedirectFixedPath bool
 
+	// If enabled, the router matches the static segments of the request path
+	// case-insensitively, the parameter values keep their original case.
+	// For example /FOO/Bar matches the route /foo/:name with name="Bar".
+	// The case-insensitive lookup is only performed if the exact lookup fails,
+	// and the request is served directly instead of being redirected by
+	// RedirectFixedPath, which is still used for fixing other parts of path,
+	// such as ../ and trailing slashes.
+	CaseInsensitive bool
+
+	// If enabled, the request path is cleaned by CleanPath before lookup, for
+	// example, /foo//bar and /foo/../foo/bar are matched by the route /foo/bar
+	// directly, instead of being redirected by RedirectFixedPath.
+	// Note that the cleaned path is written back to the request URL, so that
+	// handlers observe the cleaned path.
+	CleanPathBeforeMatch bool
+
+	// If enabled, a catch-all parameter also matches an empty value, for
+	// example, /static is matched by the route /static/*filepath with
+	// filepath="", instead of being redirected to /static/ by
+	// RedirectTrailingSlash.
+	MatchEmptyCatchAll bool
+
+	// If enabled, the router matches the escaped request path, and the
+	// parameter values are unescaped by url.PathUnescape, so that an encoded
+	// slash is a part of a parameter value rather than a path separator, for
+	// example, /files/a%2Fb is matched by the route /files/:name with
+	// name="a/b". The static segments of routes are matched in the decoded
+	// form regardless of the encoding of the request path, for example, both
+	// /caf%C3%A9/a and /caf%C3%A9/a%2Fb are matched by the route /café/:name.
+	UnescapePathValues bool
+
+	// If enabled, the router always matches the escaped request path returned
+	// by url.URL.EscapedPath, instead of the decoded path, and the parameter
+	// values are kept escaped, for example, /files/a%2Fb is matched by the
+	// route /files/:name with name="a%2Fb". It is intended for the routers
+	// fronting services that care about the raw path.
+	// Unlike UnescapePathValues, the escaped path is used even if it is the
+	// default encoding, so that the static segments of routes containing
+	// characters to be escaped, such as spaces and non-ASCII characters, must
+	// be registered in the escaped form, such as "/caf%C3%A9". The parameter
+	// values are unescaped if UnescapePathValues is enabled as well.
+	UseRawPath bool
+
+	// The name of the header which overrides the method of POST requests, such
+	// as "X-HTTP-Method-Override", it is useful for the clients which can only
+	// send GET and POST requests. The request method is replaced with the
+	// uppercased header value before matching, so that handlers observe the
+	// overridden method.
+	// Note that the override bypasses the method based protections of browsers
+	// and proxies, such as CORS preflight requests, it should only be enabled
+	// for trusted clients, for example, by a middleware of Application.Use
+	// which deletes the header of the requests from other origins.
+	MethodOverrideHeader string
+
+	// The name of the form field which overrides the method of POST requests,
+	// such as "_method", see MethodOverrideHeader. The header takes precedence
+	// over the form field. Only the body of "application/x-www-form-urlencoded"
+	// requests is parsed to read the field, and it is limited by MaxBodySize,
+	// or 10MB as http.Request.ParseForm does if MaxBodySize is zero, since the
+	// route limits are not known before matching.
+	MethodOverrideForm string
+
+	// The status code of the redirections made by RedirectTrailingSlash and
+	// RedirectFixedPath, it is applied to all request methods.
+	// If it is zero, 301 is used for Get requests and 308 for all other
+	// request methods.
+	// Note that some clients change the request method to GET while following
+	// 301 and 302 redirections.
+	RedirectCode int
+
+	// An optional function that is called right before the redirections made
+	// by RedirectTrailingSlash and RedirectFixedPath, to is the redirect URL.
+	// If it returns true, the response is considered to be written by the
+	// function, and the default redirection is suppressed.
+	OnRedirect func(w http.ResponseWriter, req *http.Request, to string) bool
+
+	// An optional function that is called after a route is matched, and
+	// before the middlewares and the handler are invoked, regardless of
+	// SaveMatchedRoute. The params of the request are available by GetParams.
+	// It is useful to label request metrics with route.Pattern() rather than
+	// the request path.
+	OnMatch func(route *Route, req *http.Request)
+
+	// An optional function that is called after a deprecated route is matched,
+	// see RouteDeprecated. It is useful for logging the usage of deprecated
+	// routes.
+	OnDeprecated func(route *Route, req *http.Request)
+
 	// If enabled, the router checks if another method is allowed for the
 	// current route, if the current request can not be routed.
 	// If this is the case, the request is answered with 'Method Not Allowed'
@@ -122,6 +380,17 @@ type Router struct {
 	// handler.
 	HandleMethodNotAllowed bool
 
+	// If enabled, HEAD requests are served by the GET handler of the path,
+	// if there is no HEAD handler, the response body is discarded.
+	HandleHEAD bool
+
+	// If enabled, HEAD is advertised in the "Allow" header of OPTIONS and 405
+	// responses, including the paths which only have a GET handler if
+	// HandleHEAD is enabled. Otherwise HEAD is never advertised, even if it
+	// has a handler. It is enabled by NewRouter, note that a Router created
+	// without NewRouter does not advertise HEAD unless it is set.
+	AdvertiseHEAD bool
+
 	// If enabled, the router automatically replies to OPTIONS requests.
 	// Custom OPTIONS handlers take priority over automatic replies.
 	HandleOPTIONS bool
@@ -132,11 +401,38 @@ type Router struct {
 	// The "Allowed" header is set before calling the handler.
 	GlobalOPTIONS http.Handler
 
+	// An optional function that produces the "Allow" header value of the
+	// automatic OPTIONS responses and the 405 responses from the allowed
+	// methods, which are sorted alphabetically and include OPTIONS, for
+	// example, to exclude HEAD or to use a specific order. The allowed methods
+	// retrieved by GetAllowedMethods are not affected.
+	AllowHeaderFunc func(methods []string) string
+
+	// If enabled, the automatic OPTIONS responses also write the allowed
+	// methods as the body, which is a JSON array if the client prefers
+	// "application/json" to "text/plain" by the Accept header, such as
+	// ["GET","OPTIONS"], or a plain text list otherwise, such as
+	// "GET, OPTIONS". It is ignored if GlobalOPTIONS is set.
+	OPTIONSBody bool
+
+	// If enabled, the default 405 responses suggest the allowed methods in
+	// the body, such as "Method POST not allowed on /users; try DELETE, GET",
+	// OPTIONS is not suggested. It is ignored if MethodNotAllowed is set.
+	MethodNotAllowedBody bool
+
+	// Function to handle panics recovered from http handlers.
+	// It should be used to generate a error page and return the http error code
+	// 500 (Internal Server Error).
+	// The handler can be used to keep your server from crashing because of
+	// unrecovered panics.
+	PanicHandler func(http.ResponseWriter, *http.Request, interface{})
+
 	// Cached value of global (*) allowed methods
 	globalAllowed string
 
 	// Configurable http.Handler which is called when no matching route is
 	// found. If it is not set, http.NotFound is used.
+	// See RouteGroupNotFound for the NotFound handlers of route groups.
 	NotFound http.Handler
 
 	// Configurable http.Handler which is called when a request
@@ -158,31 +454,315 @@ func NewRouter() *Router {
 		RedirectFixedPath:      true,
 		HandleMethodNotAllowed: true,
 		HandleOPTIONS:          true,
+		AdvertiseHEAD:          true,
 	}
 }
 
 func (r *Router) getParams() *Params {
+	if r.DisableParamsPool {
+		ps := make(Params, 0, r.maxParams)
+		return &ps
+	}
 	ps := r.paramsPool.Get().(*Params)
 	*ps = (*ps)[0:0] // reset slice
 	return ps
 }
 
 func (r *Router) putParams(ps *Params) {
+	if r.DisableParamsPool {
+		return
+	}
 	r.paramsPool.Put(ps)
 }
 
+// Use registers middlewares which will be applied to all matched routes,
+// middlewares are applied at dispatch time in the order of registration,
+// so that routes registered before calling Use are affected as well.
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// NotFoundFunc is a shortcut of setting Router.NotFound to http.HandlerFunc(handle),
+// a nil handle restores the default behavior.
+func (r *Router) NotFoundFunc(handle http.HandlerFunc) {
+	if handle == nil {
+		r.NotFound = nil
+		return
+	}
+	r.NotFound = handle
+}
+
+// MethodNotAllowedFunc is a shortcut of setting Router.MethodNotAllowed to http.HandlerFunc(handle),
+// a nil handle restores the default behavior.
+func (r *Router) MethodNotAllowedFunc(handle http.HandlerFunc) {
+	if handle == nil {
+		r.MethodNotAllowed = nil
+		return
+	}
+	r.MethodNotAllowed = handle
+}
+
+// NamedRoute returns the route of the given name, the second return value
+// reports whether the route exists.
+func (r *Router) NamedRoute(name string) (*Route, bool) {
+	route, ok := r.routes[name]
+	return route, ok
+}
+
+// NamedRoutes returns all named routes, the returned map is a copy, it is
+// safe to modify it.
+func (r *Router) NamedRoutes() map[string]*Route {
+	routes := make(map[string]*Route, len(r.routes))
+	for name, route := range r.routes {
+		routes[name] = route
+	}
+	return routes
+}
+
 // URL creates an url with the given route name and arguments.
 func (r *Router) URL(name string, args ...string) (*url.URL, error) {
-	if route, ok := r.routes[name]; ok {
+	if route, ok := r.NamedRoute(name); ok {
 		return route.URL(args...)
 	}
 
 	return nil, fmt.Errorf("route %q does not exist", name)
 }
 
+// URLQuery is like URL, but also attaches the given query parameters, which
+// are encoded by url.Values.Encode:
+//  router.URLQuery("search", url.Values{"q": {"go"}, "page": {"2"}}) // /search?page=2&q=go
+func (r *Router) URLQuery(name string, params url.Values, args ...string) (*url.URL, error) {
+	u, err := r.URL(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0 {
+		u.RawQuery = params.Encode()
+	}
+	return u, nil
+}
+
+// MustURL is like URL, but returns the URL string and panics if the URL
+// cannot be created, it is useful in template function maps.
+func (r *Router) MustURL(name string, args ...string) string {
+	u, err := r.URL(name, args...)
+	if err != nil {
+		panic(err)
+	}
+	return u.String()
+}
+
+// URLString is like URL, but returns the URL string, an empty string is
+// returned if the URL cannot be created. It is intended for trusted callers
+// whose route names and arguments are known to be valid.
+func (r *Router) URLString(name string, args ...string) string {
+	u, err := r.URL(name, args...)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// Host creates a route group of the given host pattern, the routes of the
+// group are only matched for the requests of the host, and the requests of
+// the host are only matched against the routes of the host.
+// The pattern is either an exact host, such as "api.example.com", or a
+// wildcard host, such as "*.example.com", which matches any subdomain, the
+// matched subdomain is saved as a parameter named "subdomain".
+// The port of request host is ignored.
+func (r *Router) Host(pattern string, opts ...RouteGroupOption) *RouteGroup {
+	group := &RouteGroup{router: r, host: strings.ToLower(pattern)}
+	for _, opt := range opts {
+		opt(group)
+	}
+	r.addGroupNotFound(group)
+	return group
+}
+
 // Group creates route group with the given path and optional route options.
 func (r *Router) Group(path string, opts ...RouteGroupOption) *RouteGroup {
-	return newRouteGroup(r, path, opts...)
+	group := newRouteGroup(r, path, opts...)
+	r.addGroupNotFound(group)
+	return group
+}
+
+func (r *Router) addGroupNotFound(group *RouteGroup) {
+	if group.notFound != nil {
+		r.notFoundGroups = append(r.notFoundGroups, group)
+	}
+}
+
+// notFoundHandler returns the NotFound handler of the route group with the
+// longest path prefix of the given path, host is the matched host pattern.
+// Router.NotFound is returned if there is no such group.
+func (r *Router) notFoundHandler(host, path string) http.Handler {
+	handler, matched := r.NotFound, -1
+	for _, group := range r.notFoundGroups {
+		if group.host == host && len(group.path) > matched && hasPathPrefix(path, group.path) {
+			handler, matched = group.notFound, len(group.path)
+		}
+	}
+	return handler
+}
+
+// hasPathPrefix reports whether the path is under the given prefix, which
+// does not end with a slash unless it is the root path.
+func hasPathPrefix(path, prefix string) bool {
+	if prefix == "" || prefix == "/" || path == prefix {
+		return true
+	}
+	return strings.HasPrefix(path, prefix) && path[len(prefix)] == '/'
+}
+
+// anyMethods is the set of methods registered by Any.
+var anyMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPost,
+	http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// routeNameless is a route option that drops the name of a route, it is used
+// to avoid registering the same route name multiple times.
+func routeNameless(r *Route) {
+	r.name = ""
+}
+
+// Any registers the handle for GET, HEAD, OPTIONS, POST, PUT, PATCH and DELETE methods.
+// The route name given by opts, if any, is only registered with the GET route,
+// so that it can still be used to generate URLs.
+func (r *Router) Any(path string, handle http.HandlerFunc, opts ...RouteOption) {
+	r.Match(anyMethods, path, handle, opts...)
+}
+
+// Match registers the handle for the given methods.
+// The route name given by opts, if any, is only registered with the first method,
+// so that it can still be used to generate URLs.
+func (r *Router) Match(methods []string, path string, handle http.HandlerFunc, opts ...RouteOption) {
+	for i, method := range methods {
+		r.HandleFunc(method, path, handle, opts...)
+		if i == 0 {
+			opts = append(opts[:len(opts):len(opts)], routeNameless)
+		}
+	}
+}
+
+// Mount attaches the routes of the sub router under the given prefix, just like
+// registering them through a route group of the prefix, so that the route names
+// are prefixed as well, e.g. "user" becomes "/prefix/user".
+// The middlewares of the sub router are applied to the mounted routes.
+// The routes are copied, routes registered to the sub router after mounting
+// are not affected.
+// Only the routes, including the host routes, and the middlewares are taken
+// from the sub router, its router-level settings, such as NotFound, the
+// NotFound handlers of its groups and the configuration fields, are ignored,
+// the ones of r apply instead.
+func (r *Router) Mount(prefix string, sub *Router) {
+	group := r.Group(prefix)
+	sub.eachRoute(func(method string, route *Route) {
+		// the middlewares of the sub router and the route are applied around
+		// the target, so that the fallbacks of the route are kept.
+		middleware := func(next http.Handler) http.Handler {
+			return Chain(route.wrap(next), sub.middlewares...)
+		}
+		group.Handle(method, route.path, route.handler, func(mounted *Route) {
+			// the whole route is copied, except the prefixed path which is
+			// parsed again, the group and the name prefix are set by the group.
+			path := mounted.path
+			*mounted = *route
+			mounted.path = path
+			mounted.params, mounted.segments = nil, nil
+			mounted.middlewares = []Middleware{middleware}
+		})
+	})
+}
+
+// HandlePrefix registers the handler for any method and any path under the
+// given prefix, the handler is called with the prefix stripped from the
+// request path, just like http.StripPrefix, for example, "/debug/pprof/heap"
+// is seen as "/heap" by the handler mounted under "/debug/pprof". It is useful
+// for mounting third-party handlers, such as pprof and metrics handlers.
+// The handler is registered as a catch-all route "prefix/*path" of MethodAny,
+// so that the routes registered for specific methods take precedence, and the
+// prefix itself is redirected to "prefix/" unless RedirectTrailingSlash is
+// disabled.
+func (r *Router) HandlePrefix(prefix string, handler http.Handler, opts ...RouteOption) {
+	if prefix == "" || prefix[0] != '/' {
+		panic("path must begin with '/' in path '" + prefix + "'")
+	}
+	if handler == nil {
+		panic("handler must not be nil")
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	r.HandleFunc(MethodAny, prefix+"/*path", func(w http.ResponseWriter, req *http.Request) {
+		path := GetParams(req).Get("path")
+		req2 := new(http.Request)
+		*req2 = *req
+		req2.URL = new(url.URL)
+		*req2.URL = *req.URL
+		req2.URL.Path = path
+		if rawPath := req.URL.RawPath; rawPath != "" {
+			req2.URL.RawPath = ""
+			if strings.HasPrefix(rawPath, prefix+"/") {
+				req2.URL.RawPath = rawPath[len(prefix):]
+			}
+		}
+		handler.ServeHTTP(w, req2)
+	}, opts...)
+	if prefix != "" {
+		r.HandleFunc(MethodAny, prefix, r.redirectSubtree)
+	}
+}
+
+// redirectSubtree redirects the request to the path with a trailing slash,
+// it is used for the subtree routes of MethodAny, which are not covered by
+// RedirectTrailingSlash. The request is passed if RedirectTrailingSlash is
+// disabled.
+func (r *Router) redirectSubtree(w http.ResponseWriter, req *http.Request) {
+	if !r.RedirectTrailingSlash || r.StrictSlash {
+		Pass(req)
+		return
+	}
+	u := *req.URL
+	setURLPath(&u, u.EscapedPath()+"/", true)
+	r.redirect(w, req, u.String(), r.redirectCode(req.Method))
+}
+
+// HandleStd registers the handler with the given http.ServeMux style pattern
+// for any method, it eases the migration from http.ServeMux:
+//  router.HandleStd("/api/", apiHandler)          // the subtree of /api/
+//  router.HandleStd("/favicon.ico", iconHandler)  // the exact path
+//  router.HandleStd("example.com/", siteHandler)  // the subtree of host
+//
+// A pattern ending with a slash matches the subtree rooted at the path, and
+// the path without the trailing slash is redirected to the subtree, unlike
+// HandlePrefix, the request path is not stripped. The pattern "/" is
+// registered as the root catch-all route, which matches any unrouted path.
+//
+// Unlike http.ServeMux, the patterns are routes rather than prefixes sorted by
+// length, a subtree pattern conflicts with other patterns under the subtree,
+// such as "/api/" and "/api/users", except the root "/". The ':' and '*'
+// characters of patterns are parameters unless they are escaped, and the
+// method and wildcard syntax of Go 1.22 patterns is not supported.
+func (r *Router) HandleStd(pattern string, handler http.Handler) {
+	if pattern == "" {
+		panic("pattern must not be empty")
+	}
+	handle := r.Handle
+	if pattern[0] != '/' {
+		i := strings.IndexByte(pattern, '/')
+		if i < 0 {
+			panic("pattern must contain a path in pattern '" + pattern + "'")
+		}
+		handle = r.Host(pattern[:i]).Handle
+		pattern = pattern[i:]
+	}
+	if pattern[len(pattern)-1] != '/' {
+		handle(MethodAny, pattern, handler)
+		return
+	}
+	handle(MethodAny, pattern+"*path", handler)
+	if pattern != "/" {
+		handle(MethodAny, pattern[:len(pattern)-1], http.HandlerFunc(r.redirectSubtree))
+	}
 }
 
 // Get is a shortcut of Router.HandleFunc(http.MethodGet, path, handle, opts ...)
@@ -192,32 +772,32 @@ func (r *Router) Get(path string, handle http.HandlerFunc, opts ...RouteOption)
 
 // Head is a shortcut of Router.HandleFunc(http.MethodHead, path, handle, opts ...)
 func (r *Router) Head(path string, handle http.HandlerFunc, opts ...RouteOption) {
-	r.HandleFunc(http.MethodHead, path, handle)
+	r.HandleFunc(http.MethodHead, path, handle, opts...)
 }
 
 // Options is a shortcut of Router.HandleFunc(http.MethodOptions, path, handle, opts ...)
 func (r *Router) Options(path string, handle http.HandlerFunc, opts ...RouteOption) {
-	r.HandleFunc(http.MethodOptions, path, handle)
+	r.HandleFunc(http.MethodOptions, path, handle, opts...)
 }
 
 // Post is a shortcut of Router.HandleFunc(http.MethodPost, path, handle, opts ...)
 func (r *Router) Post(path string, handle http.HandlerFunc, opts ...RouteOption) {
-	r.HandleFunc(http.MethodPost, path, handle)
+	r.HandleFunc(http.MethodPost, path, handle, opts...)
 }
 
 // Put is a shortcut of Router.HandleFunc(http.MethodPut, path, handle, opts ...)
 func (r *Router) Put(path string, handle http.HandlerFunc, opts ...RouteOption) {
-	r.HandleFunc(http.MethodPut, path, handle)
+	r.HandleFunc(http.MethodPut, path, handle, opts...)
 }
 
 // Patch is a shortcut of Router.HandleFunc(http.MethodPatch, path, handle, opts ...)
 func (r *Router) Patch(path string, handle http.HandlerFunc, opts ...RouteOption) {
-	r.HandleFunc(http.MethodPatch, path, handle)
+	r.HandleFunc(http.MethodPatch, path, handle, opts...)
 }
 
 // Delete is a shortcut of Router.HandleFunc(http.MethodDelete, path, handle, opts ...)
 func (r *Router) Delete(path string, handle http.HandlerFunc, opts ...RouteOption) {
-	r.HandleFunc(http.MethodDelete, path, handle)
+	r.HandleFunc(http.MethodDelete, path, handle, opts...)
 }
 
 // HandleFunc registers a new request handler function with the given path, method and optional route options.
@@ -236,43 +816,201 @@ func (r *Router) HandleFunc(method, path string, handle http.HandlerFunc, opts .
 }
 
 // Handle registers a new request handler with the given path, method and optional route options.
+//
+// The method MethodAny ("*") registers a fallback handler of the path, which
+// serves the requests of any method that has no handler for the path, such as
+// WebDAV methods in a reverse proxy.
 func (r *Router) Handle(method, path string, handler http.Handler, opts ...RouteOption) {
+	if err := checkHandle(method, path, handler); err != nil {
+		panic(err.Error())
+	}
+	b := r.newRouteBatch(false)
+	b.add(method, newRoute(path, handler, opts...))
+	b.commit()
+}
+
+// TryHandle is like Handle, but returns an error instead of panicking, such as
+// an empty method, a bad path, a nil handler, a duplicate route name or a path
+// conflicting with existing routes. It is useful for registering routes from
+// untrusted sources.
+// The router is left unchanged if an error is returned, since the route is
+// added to a copy of the route tree, which replaces the tree on success.
+func (r *Router) TryHandle(method, path string, handler http.Handler, opts ...RouteOption) error {
+	b := r.newRouteBatch(true)
+	if err := b.tryAdd(method, path, handler, opts...); err != nil {
+		return err
+	}
+	b.commit()
+	return nil
+}
+
+func checkHandle(method, path string, handler http.Handler) error {
 	if method == "" {
-		panic("method must not be empty")
+		return errors.New("method must not be empty")
 	}
 	if len(path) < 1 || path[0] != '/' {
-		panic("path must begin with '/' in path '" + path + "'")
+		return errors.New("path must begin with '/' in path '" + path + "'")
 	}
-	if r.trees == nil {
-		r.trees = make(map[string]*node)
+	if handler == nil {
+		return errors.New("handler must not be nil")
 	}
+	return nil
+}
 
-	root := r.trees[method]
-	if root == nil {
-		root = new(node)
-		r.trees[method] = root
+// routeBatch stages the routes to be added to the router, the staged routes
+// are added together by commit.
+type routeBatch struct {
+	router *Router
+	// If copyTree is true, the routes are added to copies of the route trees,
+	// so that the router is not changed before commit, even by a panic.
+	copyTree bool
+	// the staged trees keyed by host pattern and method.
+	trees    map[string]map[string]*node
+	names    map[string]bool
+	variants map[*Route][]*Route
+	routes   []*Route
+}
+
+func (r *Router) newRouteBatch(copyTree bool) *routeBatch {
+	return &routeBatch{router: r, copyTree: copyTree}
+}
 
-		r.globalAllowed = r.allowed("*", "")
+// tryAdd is like add, but builds the route and returns an error instead of
+// panicking, including the panics of route options.
+func (b *routeBatch) tryAdd(method, path string, handler http.Handler, opts ...RouteOption) (err error) {
+	if err := checkHandle(method, path, handler); err != nil {
+		return err
 	}
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%v", v)
+		}
+	}()
 
-	route := newRoute(path, handler, opts...)
+	b.add(method, newRoute(path, handler, opts...))
+	return nil
+}
+
+// add stages the route, it panics if the route can not be registered.
+func (b *routeBatch) add(method string, route *Route) {
 	if route.name != "" {
-		if _, ok := r.routes[route.name]; ok {
+		if _, ok := b.router.routes[route.name]; ok || b.names[route.name] {
 			panic("route name " + route.name + " is already registered")
 		}
-		if r.routes == nil {
-			r.routes = make(map[string]*Route)
+	}
+
+	root := b.root(route.host, method)
+	if existing := root.routeOf(route.path); route.accept != "" && existing != nil && existing.accept != "" {
+		b.addVariant(existing, route)
+	} else {
+		root.addRoute(route.path, route)
+	}
+
+	if route.name != "" {
+		if b.names == nil {
+			b.names = make(map[string]bool)
+		}
+		b.names[route.name] = true
+	}
+	b.routes = append(b.routes, route)
+}
+
+// root returns the staged tree of the given host pattern and method.
+func (b *routeBatch) root(host, method string) *node {
+	if root := b.trees[host][method]; root != nil {
+		return root
+	}
+	trees := b.router.trees
+	if host != "" {
+		trees = nil
+		if h := b.router.hosts[host]; h != nil {
+			trees = h.trees
+		}
+	}
+	root := trees[method]
+	if root == nil {
+		root = new(node)
+	} else if b.copyTree {
+		root = root.clone()
+	}
+	if b.trees == nil {
+		b.trees = make(map[string]map[string]*node)
+	}
+	if b.trees[host] == nil {
+		b.trees[host] = make(map[string]*node)
+	}
+	b.trees[host][method] = root
+	return root
+}
+
+// addVariant stages the route as a variant of the existing route which has
+// the same method and path, see RouteAccept.
+func (b *routeBatch) addVariant(existing, route *Route) {
+	variants, ok := b.variants[existing]
+	if !ok {
+		variants = b.router.variants[existing]
+	}
+	for _, v := range append([]*Route{existing}, variants...) {
+		if v.accept == route.accept {
+			panic("a route is already registered for path '" + route.path + "' and media type '" + route.accept + "'")
+		}
+	}
+	if b.variants == nil {
+		b.variants = make(map[*Route][]*Route)
+	}
+	// copies the variants on write, since they may be shared with clones.
+	b.variants[existing] = append(variants[:len(variants):len(variants)], route)
+}
+
+// commit adds the staged routes to the router.
+func (b *routeBatch) commit() {
+	r := b.router
+	for host, trees := range b.trees {
+		for method, root := range trees {
+			if host != "" {
+				r.hostRoutes(host).trees[method] = root
+				continue
+			}
+			if r.trees == nil {
+				r.trees = make(map[string]*node)
+			}
+			r.trees[method] = root
 		}
-		r.routes[route.name] = route
 	}
-	root.addRoute(path, route)
+	if _, ok := b.trees[""]; ok {
+		r.globalAllowed = allowedMethods(r.trees, "*", "")
+	}
+
+	if len(b.variants) > 0 && r.variants == nil {
+		r.variants = make(map[*Route][]*Route)
+	}
+	for existing, variants := range b.variants {
+		r.variants[existing] = variants
+	}
+
+	for _, route := range b.routes {
+		if route.name != "" {
+			if r.routes == nil {
+				r.routes = make(map[string]*Route)
+			}
+			r.routes[route.name] = route
+		}
 
-	// Update maxParams
-	if pc := countParams(path); pc > r.maxParams {
-		r.maxParams = pc
+		// Update maxParams
+		pc := countParams(route.path)
+		if isWildcardHost(route.host) {
+			pc++
+		}
+		if pc > r.maxParams {
+			r.maxParams = pc
+		}
 	}
 
-	// Lazy-init paramsPool alloc func
+	r.initParamsPool()
+}
+
+// initParamsPool lazy-inits paramsPool alloc func.
+func (r *Router) initParamsPool() {
 	if r.paramsPool.New == nil && r.maxParams > 0 {
 		r.paramsPool.New = func() interface{} {
 			ps := make(Params, 0, r.maxParams)
@@ -281,17 +1019,161 @@ func (r *Router) Handle(method, path string, handler http.Handler, opts ...Route
 	}
 }
 
+// Clone returns a copy of the router, it is useful for testing variants of
+// the configuration, such as RedirectTrailingSlash, without registering the
+// routes again.
+//
+// The route trees and the named routes are copied, so that registering routes
+// on either router does not affect the other one, but the routes themselves
+// are shared, for example, Route.SetHandler affects both routers.
+// Note that the handlers registered by ServeFiles refer to the NotFound
+// handler of the original router.
+func (r *Router) Clone() *Router {
+	c := &Router{
+		trees:                  cloneTrees(r.trees),
+		maxParams:              r.maxParams,
+		notFoundGroups:         append([]*RouteGroup(nil), r.notFoundGroups...),
+		middlewares:            append([]Middleware(nil), r.middlewares...),
+		MaxBodySize:            r.MaxBodySize,
+		DisableParamsPool:      r.DisableParamsPool,
+		EnableStats:            r.EnableStats,
+		SaveMatchedRoute:       r.SaveMatchedRoute,
+		RedirectTrailingSlash:  r.RedirectTrailingSlash,
+		StrictSlash:            r.StrictSlash,
+		RedirectFixedPath:      r.RedirectFixedPath,
+		CaseInsensitive:        r.CaseInsensitive,
+		CleanPathBeforeMatch:   r.CleanPathBeforeMatch,
+		MatchEmptyCatchAll:     r.MatchEmptyCatchAll,
+		UnescapePathValues:     r.UnescapePathValues,
+		UseRawPath:             r.UseRawPath,
+		MethodOverrideHeader:   r.MethodOverrideHeader,
+		MethodOverrideForm:     r.MethodOverrideForm,
+		RedirectCode:           r.RedirectCode,
+		OnRedirect:             r.OnRedirect,
+		OnMatch:                r.OnMatch,
+		OnDeprecated:           r.OnDeprecated,
+		HandleMethodNotAllowed: r.HandleMethodNotAllowed,
+		HandleHEAD:             r.HandleHEAD,
+		AdvertiseHEAD:          r.AdvertiseHEAD,
+		HandleOPTIONS:          r.HandleOPTIONS,
+		GlobalOPTIONS:          r.GlobalOPTIONS,
+		AllowHeaderFunc:        r.AllowHeaderFunc,
+		OPTIONSBody:            r.OPTIONSBody,
+		MethodNotAllowedBody:   r.MethodNotAllowedBody,
+		PanicHandler:           r.PanicHandler,
+		globalAllowed:          r.globalAllowed,
+		NotFound:               r.NotFound,
+		MethodNotAllowed:       r.MethodNotAllowed,
+	}
+	if r.hosts != nil {
+		c.hosts = make(map[string]*hostRoutes, len(r.hosts))
+		for pattern, host := range r.hosts {
+			c.hosts[pattern] = &hostRoutes{pattern: host.pattern, trees: cloneTrees(host.trees)}
+		}
+	}
+	if r.variants != nil {
+		c.variants = make(map[*Route][]*Route, len(r.variants))
+		for route, variants := range r.variants {
+			c.variants[route] = variants
+		}
+	}
+	if r.routes != nil {
+		c.routes = make(map[string]*Route, len(r.routes))
+		for name, route := range r.routes {
+			c.routes[name] = route
+		}
+	}
+	c.initParamsPool()
+	return c
+}
+
+func cloneTrees(trees map[string]*node) map[string]*node {
+	if trees == nil {
+		return nil
+	}
+	c := make(map[string]*node, len(trees))
+	for method, root := range trees {
+		c[method] = root.clone()
+	}
+	return c
+}
+
+// RouteDef is the definition of a route, see Router.Register.
+type RouteDef struct {
+	Method  string
+	Path    string
+	Handler http.Handler
+	Name    string
+	Options []RouteOption
+}
+
+func (def RouteDef) options() []RouteOption {
+	if def.Name == "" {
+		return def.Options
+	}
+	return append(def.Options[:len(def.Options):len(def.Options)], RouteName(def.Name))
+}
+
+// Register registers the given route definitions, it is useful for loading
+// routes from a central registry. Unlike Handle, it does not panic, the whole
+// slice is validated before registering any route, and the first error is
+// returned with the index of the definition, such as a bad path or a duplicate
+// route name, no route is registered in that case.
+func (r *Router) Register(routes []RouteDef) error {
+	b := r.newRouteBatch(true)
+	for i, def := range routes {
+		if err := b.tryAdd(def.Method, def.Path, def.Handler, def.options()...); err != nil {
+			return fmt.Errorf("route definition %d (%s %s): %v", i, def.Method, def.Path, err)
+		}
+	}
+	b.commit()
+	return nil
+}
+
 // ServeFiles serves files from the given file system root.
 // The path must end with "/*filepath", files are then served from the local
 // path /defined/root/dir/*filepath.
 // For example if root is "/etc" and *filepath is "passwd", the local file
 // "/etc/passwd" would be served.
-// Internally a http.FileServer is used, therefore http.NotFound is used instead
-// of the Router's NotFound handler.
+// Internally a http.FileServer is used, if the file does not exist, the
+// Router's NotFound handler is called, and falls back to http.NotFound if it
+// is not set. The file serving is aborted once the request context is done,
+// and 503 Service Unavailable is replied without touching the file system if
+// it is already done, such as an expired upstream deadline, see
+// ServeFilesWithOptions for more options.
 // To use the operating system's file system implementation,
 // use http.Dir:
 //     router.ServeFiles("/src/*filepath", http.Dir("/var/www"))
 func (r *Router) ServeFiles(path string, root http.FileSystem) {
+	r.ServeFilesWithOptions(path, root, FileServeOptions{})
+}
+
+// FileServeOptions contains the options of Router.ServeFilesWithOptions.
+type FileServeOptions struct {
+	// CacheControl is the Cache-Control header value of the successful
+	// responses, such as "public, max-age=86400".
+	CacheControl string
+
+	// OnError is called if an error occurs while writing a file, the error
+	// is either returned by the underlying response writer, or the error of
+	// the request context, such as context.Canceled if the client disconnected
+	// and context.DeadlineExceeded if the deadline expired before serving.
+	OnError func(req *http.Request, err error)
+
+	// Precompressed enables serving the precompressed variant of a file,
+	// such as "app.js.br" or "app.js.gz" for "app.js", if the client accepts
+	// the encoding and the variant exists, brotli is preferred over gzip.
+	// The Content-Type is determined by the extension of the original file,
+	// and range requests are applied to the compressed content.
+	// Otherwise the original file is served.
+	Precompressed bool
+}
+
+// ServeFilesWithOptions is like ServeFiles, but also applies the given options.
+// The file serving is aborted as soon as the request context is done, for
+// example, when the client disconnects during a large file download, and
+// 503 Service Unavailable is replied if the context is done before serving.
+func (r *Router) ServeFilesWithOptions(path string, root http.FileSystem, opts FileServeOptions) {
 	if len(path) < 10 || path[len(path)-10:] != "/*filepath" {
 		panic("path must end with /*filepath in path '" + path + "'")
 	}
@@ -299,19 +1181,360 @@ func (r *Router) ServeFiles(path string, root http.FileSystem) {
 	fileServer := http.FileServer(root)
 
 	r.Get(path, func(w http.ResponseWriter, req *http.Request) {
+		if err := req.Context().Err(); err != nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			if opts.OnError != nil {
+				opts.OnError(req, err)
+			}
+			return
+		}
+		originalPath := req.URL.Path
 		req.URL.Path = GetParams(req).Get("filepath")
-		fileServer.ServeHTTP(w, req)
+
+		var nfw *notFoundResponseWriter
+		notFound := r.notFoundHandler("", originalPath)
+		if notFound != nil {
+			nfw = &notFoundResponseWriter{responseWriter: newResponseWriter(w)}
+			w = nfw
+		}
+		fw := &fileResponseWriter{responseWriter: newResponseWriter(w), req: req, cacheControl: opts.CacheControl}
+		if opts.Precompressed {
+			fw.Header().Add("Vary", "Accept-Encoding")
+		}
+		if !opts.Precompressed || !servePrecompressed(fw, req, root, req.URL.Path) {
+			fileServer.ServeHTTP(fw, req)
+		}
+		if fw.err != nil && opts.OnError != nil {
+			opts.OnError(req, fw.err)
+		}
+		if nfw != nil && nfw.notFound {
+			req.URL.Path = originalPath
+			notFound.ServeHTTP(nfw.ResponseWriter, req)
+		}
 	})
 }
 
+// ServeFile registers GET and HEAD routes of the given path which serve the
+// named file by http.ServeFile, such as "/favicon.ico" for
+// "./static/favicon.ico". The conditional requests, such as If-Modified-Since,
+// and range requests are supported. If the file does not exist, the Router's
+// NotFound handler is called, and falls back to http.NotFound if it is not set.
+func (r *Router) ServeFile(path, filename string, opts ...RouteOption) {
+	handle := func(w http.ResponseWriter, req *http.Request) {
+		notFound := r.notFoundHandler("", req.URL.Path)
+		if notFound == nil {
+			http.ServeFile(w, req, filename)
+			return
+		}
+		nfw := &notFoundResponseWriter{responseWriter: newResponseWriter(w)}
+		http.ServeFile(nfw, req, filename)
+		if nfw.notFound {
+			notFound.ServeHTTP(w, req)
+		}
+	}
+	r.Match([]string{http.MethodGet, http.MethodHead}, path, handle, opts...)
+}
+
+// fileResponseWriter sets the Cache-Control header of successful responses,
+// and stops writing once the request context is done.
+type fileResponseWriter struct {
+	*responseWriter
+	req          *http.Request
+	cacheControl string
+	wroteHeader  bool
+	err          error
+}
+
+func (w *fileResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.cacheControl != "" && (code == http.StatusOK || code == http.StatusPartialContent || code == http.StatusNotModified) {
+		w.Header().Set("Cache-Control", w.cacheControl)
+	}
+	w.responseWriter.WriteHeader(code)
+}
+
+func (w *fileResponseWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if err := w.req.Context().Err(); err != nil {
+		w.err = err
+		return 0, err
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.responseWriter.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// ReadFrom checks the request context once before delegating to the
+// underlying io.ReaderFrom, so that the files can still be sent by sendfile.
+// A client gone during the copy fails the copy itself.
+func (w *fileResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if err := w.req.Context().Err(); err != nil {
+		w.err = err
+		return 0, err
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.responseWriter.ReadFrom(src)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+var precompressedEncodings = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// servePrecompressed serves the precompressed variant of the named file if
+// possible, it reports whether the variant was served.
+func servePrecompressed(w http.ResponseWriter, req *http.Request, root http.FileSystem, name string) bool {
+	if strings.HasSuffix(name, "/") || strings.HasSuffix(name, "/index.html") {
+		// leaves directories and index redirection to the file server.
+		return false
+	}
+	ctype := mime.TypeByExtension(filepath.Ext(name))
+	if ctype == "" {
+		return false
+	}
+
+	accept := req.Header.Get("Accept-Encoding")
+	for _, enc := range precompressedEncodings {
+		if !acceptsEncoding(accept, enc.encoding) {
+			continue
+		}
+		f, err := root.Open(name + enc.ext)
+		if err != nil {
+			continue
+		}
+		d, err := f.Stat()
+		if err != nil || d.IsDir() {
+			f.Close()
+			continue
+		}
+		w.Header().Set("Content-Type", ctype)
+		w.Header().Set("Content-Encoding", enc.encoding)
+		http.ServeContent(w, req, name, d.ModTime(), f)
+		f.Close()
+		return true
+	}
+	return false
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header value accepts
+// the given encoding, an encoding with zero quality is not acceptable.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params := part, ""
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			name, params = part[:i], part[i+1:]
+		}
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		params = strings.TrimSpace(params)
+		if strings.HasPrefix(params, "q=") {
+			if q, err := strconv.ParseFloat(params[2:], 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// notFoundResponseWriter intercepts the 404 response, so that the
+// Router's NotFound handler can be used instead.
+type notFoundResponseWriter struct {
+	*responseWriter
+	notFound bool
+}
+
+func (w *notFoundResponseWriter) WriteHeader(code int) {
+	if code == http.StatusNotFound {
+		w.notFound = true
+		// drops the headers set by http.Error.
+		h := w.Header()
+		h.Del("Content-Type")
+		h.Del("X-Content-Type-Options")
+		return
+	}
+	w.responseWriter.WriteHeader(code)
+}
+
+func (w *notFoundResponseWriter) Write(p []byte) (int, error) {
+	if w.notFound {
+		return len(p), nil
+	}
+	return w.responseWriter.Write(p)
+}
+
+func (w *notFoundResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.notFound {
+		return io.Copy(ioutil.Discard, src)
+	}
+	return w.responseWriter.ReadFrom(src)
+}
+
+// HasRoute reports whether a route was registered with exactly the given method
+// and path, the path is treated literally, rather than a request path to be
+// matched, for example, HasRoute(http.MethodGet, "/users/:id") returns true
+// only if "/users/:id" was registered, "/users/:name" or "/users/foo" do not count.
+// The routes of hosts are excluded.
+func (r *Router) HasRoute(method, path string) bool {
+	if root := r.trees[method]; root != nil {
+		return root.routeOf(path) != nil
+	}
+	return false
+}
+
+// RemoveRoute removes the route registered with exactly the given method and
+// path, the path is treated literally as HasRoute does, it reports whether
+// the route was removed. The name of the route is released as well.
+// The routes of hosts are excluded.
+//
+// The route is cleared from the tree nodes, but the nodes are kept, so that the
+// path can be registered again, while the wildcards of the removed route still
+// conflict with different wildcards in the same position, for example,
+// "/users/:name" can not be registered after removing "/users/:id".
+// RemoveRoute must not be called concurrently with serving requests.
+func (r *Router) RemoveRoute(method, path string) bool {
+	root := r.trees[method]
+	if root == nil {
+		return false
+	}
+	route := root.routeOf(path)
+	if route == nil {
+		return false
+	}
+
+	root.removeRoute(route)
+	r.globalAllowed = allowedMethods(r.trees, "*", "")
+	for _, route := range append([]*Route{route}, r.variants[route]...) {
+		if route.name != "" && r.routes[route.name] == route {
+			delete(r.routes, route.name)
+		}
+	}
+	delete(r.variants, route)
+	return true
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method string `json:"method"`
+	Host   string `json:"host,omitempty"`
+	Path   string `json:"path"`
+	Name   string `json:"name,omitempty"`
+	Route  *Route `json:"-"`
+}
+
+// eachRoute calls fn for each registered route exactly once.
+func (r *Router) eachRoute(fn func(method string, route *Route)) {
+	each := func(method string, route *Route) {
+		fn(method, route)
+		for _, variant := range r.variants[route] {
+			fn(method, variant)
+		}
+	}
+	walkTrees(r.trees, each)
+	for _, host := range r.hosts {
+		walkTrees(host.trees, each)
+	}
+}
+
+func walkTrees(trees map[string]*node, fn func(method string, route *Route)) {
+	for method, root := range trees {
+		visited := make(map[*Route]bool)
+		root.walk(func(route *Route) {
+			if !visited[route] {
+				visited[route] = true
+				fn(method, route)
+			}
+		})
+	}
+}
+
+// Routes returns all registered routes sorted by method, path then host.
+func (r *Router) Routes() []RouteInfo {
+	var routes []RouteInfo
+	r.eachRoute(func(method string, route *Route) {
+		routes = append(routes, RouteInfo{
+			Method: method,
+			Host:   route.host,
+			Path:   route.path,
+			Name:   route.name,
+			Route:  route,
+		})
+	})
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Host < routes[j].Host
+	})
+	return routes
+}
+
+// DumpRoutes writes a human-readable table of the registered routes to w,
+// one route per line in the order of Routes, the host of host routes is
+// prepended to the path:
+//  METHOD  PATH        NAME
+//  GET     /           home
+//  GET     /users/:id  user
+func (r *Router) DumpRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tNAME")
+	for _, route := range r.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", route.Method, route.Host+route.Path, route.Name)
+	}
+	return tw.Flush()
+}
+
+// MarshalRoutesJSON returns the JSON encoding of the registered routes in
+// the order of Routes.
+func (r *Router) MarshalRoutesJSON() ([]byte, error) {
+	routes := r.Routes()
+	if routes == nil {
+		routes = []RouteInfo{}
+	}
+	return json.Marshal(routes)
+}
+
 // Lookup allows the manual lookup of a method + path combo.
 // This is e.g. useful to build a framework around this router.
 // If the path was found, it returns the handle function and the path parameter
 // values. Otherwise the third return value indicates whether a redirection to
 // the same path with an extra / without the trailing slash should be performed.
+//
+// It is also a way to retrieve the matched route without turning on
+// SaveMatchedRoute, such as getting the route pattern in middleware:
+//  route, _, _ := router.Lookup(req.Method, req.URL.Path)
+//  if route != nil {
+//      pattern := route.Pattern() // "/users/:id"
+//  }
 func (r *Router) Lookup(method, path string) (*Route, Params, bool) {
 	if root := r.trees[method]; root != nil {
-		route, ps, tsr := root.getValue(path, r.getParams)
+		route, ps, tsr := r.getValue(root, path)
 		if route == nil {
 			return nil, nil, tsr
 		}
@@ -323,30 +1546,158 @@ func (r *Router) Lookup(method, path string) (*Route, Params, bool) {
 	return nil, nil, false
 }
 
+// LookupInto is like Lookup, but saves the path parameter values into the
+// given buffer instead of one taken from the pool, it reports whether a route
+// was found. The buffer is reset before lookup, and it is replaced by a larger
+// one if its capacity is insufficient, so that reusing the same buffer across
+// lookups avoids allocations:
+//  ps := make(clevergo.Params, 0, 8)
+//  for _, msg := range messages {
+//      if route, ok := router.LookupInto(msg.Method, msg.Path, &ps); ok {
+//          // ...
+//      }
+//  }
+//
+// The caller owns the buffer, the router never retains it, and the values are
+// overwritten by the next lookup into the same buffer.
+func (r *Router) LookupInto(method, path string, ps *Params) (*Route, bool) {
+	root := r.trees[method]
+	if root == nil {
+		return nil, false
+	}
+	if cap(*ps) < int(r.maxParams) {
+		*ps = make(Params, 0, r.maxParams)
+	}
+	*ps = (*ps)[:0]
+	params := func() *Params {
+		return ps
+	}
+
+	route, _, _ := root.getValue(path, params)
+	if route == nil && r.CaseInsensitive {
+		if fixedPath, found := root.findCaseInsensitivePath(path, false); found {
+			*ps = (*ps)[:0]
+			route, _, _ = root.getValue(fixedPath, params)
+		}
+	}
+	if route == nil {
+		*ps = (*ps)[:0]
+		return nil, false
+	}
+	return route, true
+}
+
+// getValue looks up the path in the given tree, and falls back to
+// case-insensitive lookup if CaseInsensitive is enabled.
+func (r *Router) getValue(root *node, path string) (route *Route, ps *Params, tsr bool) {
+	route, ps, tsr = root.getValue(path, r.getParams)
+	if route == nil && r.CaseInsensitive {
+		if fixedPath, found := root.findCaseInsensitivePath(path, false); found {
+			if ps != nil {
+				r.putParams(ps)
+			}
+			route, ps, tsr = root.getValue(fixedPath, r.getParams)
+		}
+	}
+	if route == nil && tsr && r.MatchEmptyCatchAll && path != "" && path[len(path)-1] != '/' {
+		catchAll, cps, _ := root.getValue(path+"/", r.getParams)
+		if catchAll != nil && isCatchAllRoute(catchAll) && (*cps)[len(*cps)-1].Value == "/" {
+			if ps != nil {
+				r.putParams(ps)
+			}
+			(*cps)[len(*cps)-1].Value = ""
+			return catchAll, cps, false
+		}
+		if cps != nil {
+			r.putParams(cps)
+		}
+	}
+	// The params of an unmatched path, such as a route rejected by its
+	// constraints, are put back to the pool.
+	if route == nil && ps != nil {
+		r.putParams(ps)
+		ps = nil
+	}
+	return
+}
+
+// isCatchAllRoute reports whether the route path ends with a catch-all parameter.
+func isCatchAllRoute(route *Route) bool {
+	i := strings.LastIndexByte(route.path, '/')
+	return i >= 0 && i+1 < len(route.path) && route.path[i+1] == '*'
+}
+
+// Methods returns the sorted methods of the routes that match the given request
+// path, for example, Methods("/users/1") returns [GET PUT] if the routes GET
+// /users/:id and PUT /users/:id were registered. The routes of hosts and the
+// wildcard method MethodAny are excluded. It is useful for building OPTIONS
+// responses or documentation.
+func (r *Router) Methods(path string) []string {
+	var methods []string
+	for method, root := range r.trees {
+		if method == MethodAny {
+			continue
+		}
+		if route, _, _ := root.getValue(path, nil); route != nil {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
 func (r *Router) allowed(path, reqMethod string) (allow string) {
+	if path == "*" {
+		return r.allowHEAD(r.globalAllowed)
+	}
+	return r.allowHEAD(allowedMethods(r.trees, path, reqMethod))
+}
+
+// allowHEAD adds HEAD to the allowed methods if GET is allowed and HEAD is
+// served by GET, or removes HEAD from them, according to Router.HandleHEAD
+// and Router.AdvertiseHEAD.
+func (r *Router) allowHEAD(allow string) string {
+	if allow == "" || r.AdvertiseHEAD && !r.HandleHEAD {
+		return allow
+	}
+	methods := strings.Split(allow, ", ")
+	i := sort.SearchStrings(methods, http.MethodHead)
+	hasHEAD := i < len(methods) && methods[i] == http.MethodHead
+	if !r.AdvertiseHEAD {
+		if !hasHEAD {
+			return allow
+		}
+		methods = append(methods[:i], methods[i+1:]...)
+	} else {
+		j := sort.SearchStrings(methods, http.MethodGet)
+		if hasHEAD || j == len(methods) || methods[j] != http.MethodGet {
+			return allow
+		}
+		methods = append(methods[:i], append([]string{http.MethodHead}, methods[i:]...)...)
+	}
+	return strings.Join(methods, ", ")
+}
+
+func allowedMethods(trees map[string]*node, path, reqMethod string) (allow string) {
 	allowed := make([]string, 0, 9)
 
 	if path == "*" { // server-wide
-		// empty method is used for internal calls to refresh the cache
-		if reqMethod == "" {
-			for method := range r.trees {
-				if method == http.MethodOptions {
-					continue
-				}
-				// Add request method to list of allowed methods
-				allowed = append(allowed, method)
+		for method, root := range trees {
+			// Skip the methods whose routes have all been removed
+			if method == http.MethodOptions || method == MethodAny || !root.hasRoutes() {
+				continue
 			}
-		} else {
-			return r.globalAllowed
+			// Add request method to list of allowed methods
+			allowed = append(allowed, method)
 		}
 	} else { // specific path
-		for method := range r.trees {
+		for method := range trees {
 			// Skip the requested method - we already tried this one
-			if method == reqMethod || method == http.MethodOptions {
+			if method == reqMethod || method == http.MethodOptions || method == MethodAny {
 				continue
 			}
 
-			handle, _, _ := r.trees[method].getValue(path, nil)
+			handle, _, _ := trees[method].getValue(path, nil)
 			if handle != nil {
 				// Add request method to list of allowed methods
 				allowed = append(allowed, method)
@@ -373,38 +1724,391 @@ func (r *Router) allowed(path, reqMethod string) (allow string) {
 	return
 }
 
+// withAllowedMethods attaches the allowed methods of the given Allow header
+// value onto the request.
+func withAllowedMethods(req *http.Request, allow string) *http.Request {
+	return withContext(req, func(c *Context) {
+		c.AllowedMethods = strings.Split(allow, ", ")
+	})
+}
+
+// setAllowHeader sets the "Allow" header of the given allowed methods, see
+// Router.AllowHeaderFunc.
+func (r *Router) setAllowHeader(w http.ResponseWriter, allow string) {
+	if r.AllowHeaderFunc != nil {
+		allow = r.AllowHeaderFunc(strings.Split(allow, ", "))
+	}
+	w.Header().Set("Allow", allow)
+}
+
+// methodNotAllowedMessage returns the 405 message which suggests the allowed
+// methods of the given Allow header value except OPTIONS, see
+// Router.MethodNotAllowedBody.
+func methodNotAllowedMessage(req *http.Request, allow string) string {
+	methods := strings.Split(allow, ", ")
+	suggestions := make([]string, 0, len(methods))
+	for _, method := range methods {
+		if method != http.MethodOptions {
+			suggestions = append(suggestions, method)
+		}
+	}
+	msg := "Method " + req.Method + " not allowed on " + req.URL.Path
+	if len(suggestions) > 0 {
+		msg += "; try " + strings.Join(suggestions, ", ")
+	}
+	return msg
+}
+
+// writeAllowedMethods writes the allowed methods of the given Allow header
+// value as the response body, see Router.OPTIONSBody.
+func writeAllowedMethods(w http.ResponseWriter, req *http.Request, allow string) {
+	ranges := parseAccept(req.Header.Get("Accept"))
+	if acceptQuality(ranges, "application/json") > acceptQuality(ranges, "text/plain") {
+		body, _ := json.Marshal(strings.Split(allow, ", "))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(body)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, allow)
+}
+
+// redirectCode returns the status code of the redirections made by the router.
+func (r *Router) redirectCode(method string) int {
+	if r.RedirectCode != 0 {
+		return r.RedirectCode
+	}
+	if method != http.MethodGet {
+		// Permanent Redirect, request with same method
+		return http.StatusPermanentRedirect
+	}
+	// Moved Permanently, request with Get method
+	return http.StatusMovedPermanently
+}
+
+func (r *Router) redirect(w http.ResponseWriter, req *http.Request, to string, code int) {
+	if r.OnRedirect != nil && r.OnRedirect(w, req, to) {
+		return
+	}
+	http.Redirect(w, req, to, code)
+}
+
+func (r *Router) recv(w http.ResponseWriter, req *http.Request) {
+	if rcv := recover(); rcv != nil {
+		r.PanicHandler(w, req, rcv)
+	}
+}
+
+// serve dispatches the request to the matched route of the given tree, and
+// falls back to the root catch-all route of the tree if the route passes the
+// request, it reports whether the request was passed by all of them.
+func (r *Router) serve(w http.ResponseWriter, req *http.Request, trees map[string]*node, root *node, path string, route *Route, ps *Params, hostParam *Param) bool {
+	// the routes of other trees may serve the passed request.
+	fallback := req.Method == http.MethodHead && r.HandleHEAD ||
+		req.Method != MethodAny && trees[MethodAny] != nil
+	if !r.handle(w, req, route, ps, hostParam, fallback || root.catchAll != nil && root.catchAll != route) {
+		return false
+	}
+	if root.catchAll == nil || root.catchAll == route {
+		return true
+	}
+	param, ok := root.catchAllParam(path)
+	if !ok {
+		return true
+	}
+	ps = r.getParams()
+	*ps = append(*ps, param)
+	return r.handle(w, req, root.catchAll, ps, hostParam, fallback)
+}
+
+// handle dispatches the request to the matched route, it reports whether the
+// route passed the request, see Pass. The router context is only attached if
+// it is needed, that is, there are params, Router.SaveMatchedRoute is turned
+// on, or the passed request can fall back to the fallbacks of the route or the
+// other routes, so that serving a static route does not allocate.
+func (r *Router) handle(w http.ResponseWriter, req *http.Request, route *Route, ps *Params, hostParam *Param, fallback bool) bool {
+	if variants, ok := r.variants[route]; ok {
+		w.Header().Add("Vary", "Accept")
+		if route = negotiate(req, route, variants); route == nil {
+			if ps != nil {
+				r.putParams(ps)
+			}
+			http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+			return false
+		}
+	}
+	if hostParam != nil {
+		if ps == nil {
+			ps = r.getParams()
+		}
+		*ps = append(*ps, *hostParam)
+	}
+	if ps != nil && r.UnescapePathValues && (r.UseRawPath || req.URL.RawPath != "") {
+		unescapeParams(*ps)
+	}
+	var c *Context
+	if ps != nil || r.SaveMatchedRoute || fallback || len(route.fallbacks) > 0 ||
+		len(r.middlewares) > 0 || route.hasMiddlewares() {
+		c = &Context{}
+		if ps != nil {
+			c.Params = *ps
+			r.putParams(ps)
+		}
+		if r.SaveMatchedRoute {
+			c.Route = route
+		}
+		req = req.WithContext(context.WithValue(req.Context(), ctxKey, c))
+	}
+	limit := route.maxBodySize
+	if limit == 0 {
+		limit = r.MaxBodySize
+	}
+	if limit > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(w, req.Body, limit)
+	}
+	if r.OnMatch != nil {
+		r.OnMatch(route, req)
+	}
+	if route.deprecated {
+		w.Header().Set("Deprecation", "true")
+		if !route.sunset.IsZero() {
+			w.Header().Set("Sunset", route.sunset.UTC().Format(http.TimeFormat))
+		}
+		if r.OnDeprecated != nil {
+			r.OnDeprecated(route, req)
+		}
+	}
+	Chain(route.chain(), r.middlewares...).ServeHTTP(w, req)
+	return c != nil && c.takePassed()
+}
+
+// unescapeParams unescapes the param values matched against the escaped path,
+// the invalid values are kept as is.
+func unescapeParams(ps Params) {
+	for i := range ps {
+		if value, err := url.PathUnescape(ps[i].Value); err == nil {
+			ps[i].Value = value
+		}
+	}
+}
+
+// unescapeSegments unescapes the escaped path except the escaped slashes and
+// percent signs, so that the static segments can be matched in the decoded
+// form, while the encoded slashes are still a part of the segments.
+func unescapeSegments(path string) string {
+	if strings.IndexByte(path, '%') < 0 {
+		return path
+	}
+	buf := make([]byte, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '%' && i+2 < len(path) && ishex(path[i+1]) && ishex(path[i+2]) {
+			if c := unhex(path[i+1])<<4 | unhex(path[i+2]); c != '/' && c != '%' {
+				buf = append(buf, c)
+				i += 2
+				continue
+			}
+		}
+		buf = append(buf, path[i])
+	}
+	return string(buf)
+}
+
+// escapeSegments escapes the path except the percent signs, so that the
+// existing escapes, such as the ones kept by unescapeSegments, remain as is.
+func escapeSegments(path string) string {
+	pieces := strings.Split(path, "%")
+	for i, piece := range pieces {
+		pieces[i] = (&url.URL{Path: piece}).EscapedPath()
+	}
+	return strings.Join(pieces, "%")
+}
+
+func ishex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+// setURLPath sets the path of the URL, the given path is an escaped path
+// if escaped is true.
+func setURLPath(u *url.URL, path string, escaped bool) {
+	if !escaped {
+		u.Path = path
+		return
+	}
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		u.Path, u.RawPath = unescaped, path
+	}
+}
+
+// headResponseWriter discards the response body of HEAD requests.
+type headResponseWriter struct {
+	*responseWriter
+}
+
+func (w *headResponseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *headResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(ioutil.Discard, src)
+}
+
+// Shutdown stops the router from serving new requests, which are answered
+// with 503 Service Unavailable, and waits for the active requests to finish.
+// It returns the context error if the context expires before that.
+// Unlike http.Server.Shutdown, the listeners and connections are not closed,
+// and the router can not be restarted.
+func (r *Router) Shutdown(ctx context.Context) error {
+	r.drainMu.Lock()
+	idle := r.idle
+	if idle == nil {
+		idle = make(chan struct{})
+		r.idle = idle
+	}
+	r.drainMu.Unlock()
+
+	// the requests which see draining after incrementing active are rejected,
+	// the others are waited, the last one of which closes idle.
+	atomic.StoreInt32(&r.draining, 1)
+	if atomic.LoadInt64(&r.active) == 0 {
+		r.closeIdle()
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish decrements the number of active requests, and closes idle if it is
+// the last active request while draining.
+func (r *Router) finish() {
+	if atomic.AddInt64(&r.active, -1) == 0 && atomic.LoadInt32(&r.draining) == 1 {
+		r.closeIdle()
+	}
+}
+
+func (r *Router) closeIdle() {
+	r.drainMu.Lock()
+	defer r.drainMu.Unlock()
+	select {
+	case <-r.idle:
+	default:
+		close(r.idle)
+	}
+}
+
+// maxOverrideFormSize is the body limit of reading MethodOverrideForm if
+// MaxBodySize is zero, it is the same as the limit of http.Request.ParseForm.
+const maxOverrideFormSize = 10 << 20
+
+// overrideMethod returns the overridden method of the POST request, see
+// MethodOverrideHeader and MethodOverrideForm.
+func (r *Router) overrideMethod(w http.ResponseWriter, req *http.Request) string {
+	var method string
+	if r.MethodOverrideHeader != "" {
+		method = req.Header.Get(r.MethodOverrideHeader)
+	}
+	if method == "" && r.MethodOverrideForm != "" && req.Body != nil {
+		ctype, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if ctype == "application/x-www-form-urlencoded" {
+			limit := r.MaxBodySize
+			if limit <= 0 {
+				limit = maxOverrideFormSize
+			}
+			req.Body = http.MaxBytesReader(w, req.Body, limit)
+			method = req.PostFormValue(r.MethodOverrideForm)
+		}
+	}
+	return strings.ToUpper(strings.TrimSpace(method))
+}
+
 // ServeHTTP makes the router implement the http.Handler interface.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.count(&r.stats.requests)
+	atomic.AddInt64(&r.active, 1)
+	defer r.finish()
+	if atomic.LoadInt32(&r.draining) == 1 {
+		w.Header().Set("Connection", "close")
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.PanicHandler != nil {
+		defer r.recv(w, req)
+	}
+
+	if req.Method == http.MethodPost {
+		if method := r.overrideMethod(w, req); method != "" {
+			req.Method = method
+		}
+	}
+
 	path := req.URL.Path
+	if r.CleanPathBeforeMatch {
+		if cleaned := CleanPath(path); cleaned != path {
+			req.URL.Path, req.URL.RawPath = cleaned, ""
+			path = cleaned
+		}
+	}
 
-	if root := r.trees[req.Method]; root != nil {
-		if route, ps, tsr := root.getValue(path, r.getParams); route != nil {
-			if ps != nil {
-				ctx := context.WithValue(req.Context(), paramsKey, *ps)
-				req = req.WithContext(ctx)
-				r.putParams(ps)
+	escaped := r.UseRawPath || r.UnescapePathValues && req.URL.RawPath != ""
+	if r.UseRawPath {
+		path = req.URL.EscapedPath()
+	} else if escaped {
+		path = unescapeSegments(req.URL.RawPath)
+	}
+	// setPath sets the request path to the given matched path on redirections.
+	setPath := func(path string) {
+		if escaped && !r.UseRawPath {
+			path = escapeSegments(path)
+		}
+		setURLPath(req.URL, path, escaped)
+	}
+
+	trees, allowed := r.trees, r.allowed
+	var hostParam *Param
+	var hostPattern string
+	if len(r.hosts) > 0 {
+		if host, param := r.matchHost(req.Host); host != nil {
+			trees, hostParam, hostPattern = host.trees, param, host.pattern
+			allowed = func(path, reqMethod string) string {
+				return r.allowHEAD(allowedMethods(trees, path, reqMethod))
 			}
-			if r.SaveMatchedRoute {
-				ctx := context.WithValue(req.Context(), routeKey, route)
-				req = req.WithContext(ctx)
+		}
+	}
+
+	// passed is set if a matched route passed the request, the redirections
+	// and the 405 response are skipped in that case.
+	passed := false
+	if root := trees[req.Method]; root != nil {
+		if route, ps, tsr := r.getValue(root, path); route != nil {
+			if !r.serve(w, req, trees, root, path, route, ps, hostParam) {
+				r.count(&r.stats.matches)
+				return
 			}
-			route.handler.ServeHTTP(w, req)
-			return
+			passed = true
 		} else if req.Method != http.MethodConnect && path != "/" {
-			// Moved Permanently, request with Get method
-			code := http.StatusMovedPermanently
-			if req.Method != http.MethodGet {
-				// Permanent Redirect, request with same method
-				code = http.StatusPermanentRedirect
-			}
+			code := r.redirectCode(req.Method)
 
-			if tsr && r.RedirectTrailingSlash {
+			if tsr && r.RedirectTrailingSlash && !r.StrictSlash {
 				if len(path) > 1 && path[len(path)-1] == '/' {
-					req.URL.Path = path[:len(path)-1]
+					setPath(path[:len(path)-1])
 				} else {
-					req.URL.Path = path + "/"
+					setPath(path + "/")
 				}
-				http.Redirect(w, req, req.URL.String(), code)
+				r.count(&r.stats.redirects)
+				r.redirect(w, req, req.URL.String(), code)
 				return
 			}
 
@@ -412,31 +2116,65 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			if r.RedirectFixedPath {
 				fixedPath, found := root.findCaseInsensitivePath(
 					CleanPath(path),
-					r.RedirectTrailingSlash,
+					r.RedirectTrailingSlash && !r.StrictSlash,
 				)
 				if found {
-					req.URL.Path = fixedPath
-					http.Redirect(w, req, req.URL.String(), code)
+					// The fixed path must also satisfy the route constraints.
+					if route, _, _ := root.getValue(fixedPath, nil); route != nil {
+						setPath(fixedPath)
+						r.count(&r.stats.redirects)
+						r.redirect(w, req, req.URL.String(), code)
+						return
+					}
+				}
+			}
+		}
+	}
+
+	// Serve HEAD requests by GET handler
+	if req.Method == http.MethodHead && r.HandleHEAD {
+		if root := trees[http.MethodGet]; root != nil {
+			if route, ps, _ := r.getValue(root, path); route != nil {
+				if !r.serve(exposeInterfaces(&headResponseWriter{newResponseWriter(w)}), req, trees, root, path, route, ps, hostParam) {
+					r.count(&r.stats.matches)
 					return
 				}
+				passed = true
+			}
+		}
+	}
+
+	// Serve requests of unregistered methods by the wildcard method handler
+	if root := trees[MethodAny]; root != nil && req.Method != MethodAny {
+		if route, ps, _ := r.getValue(root, path); route != nil {
+			if !r.serve(w, req, trees, root, path, route, ps, hostParam) {
+				r.count(&r.stats.matches)
+				return
 			}
+			passed = true
 		}
 	}
 
-	if req.Method == http.MethodOptions && r.HandleOPTIONS {
+	// the request is not found if all of the matched routes passed it.
+	if !passed && req.Method == http.MethodOptions && r.HandleOPTIONS {
 		// Handle OPTIONS requests
-		if allow := r.allowed(path, http.MethodOptions); allow != "" {
-			w.Header().Set("Allow", allow)
+		if allow := allowed(path, http.MethodOptions); allow != "" {
+			r.setAllowHeader(w, allow)
 			if r.GlobalOPTIONS != nil {
-				r.GlobalOPTIONS.ServeHTTP(w, req)
+				r.GlobalOPTIONS.ServeHTTP(w, withAllowedMethods(req, allow))
+			} else if r.OPTIONSBody {
+				writeAllowedMethods(w, req, allow)
 			}
 			return
 		}
-	} else if r.HandleMethodNotAllowed { // Handle 405
-		if allow := r.allowed(path, req.Method); allow != "" {
-			w.Header().Set("Allow", allow)
+	} else if !passed && r.HandleMethodNotAllowed { // Handle 405
+		if allow := allowed(path, req.Method); allow != "" {
+			r.count(&r.stats.methodNotAllowed)
+			r.setAllowHeader(w, allow)
 			if r.MethodNotAllowed != nil {
-				r.MethodNotAllowed.ServeHTTP(w, req)
+				r.MethodNotAllowed.ServeHTTP(w, withAllowedMethods(req, allow))
+			} else if r.MethodNotAllowedBody {
+				http.Error(w, methodNotAllowedMessage(req, allow), http.StatusMethodNotAllowed)
 			} else {
 				http.Error(w,
 					http.StatusText(http.StatusMethodNotAllowed),
@@ -448,8 +2186,9 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 
 	// Handle 404
-	if r.NotFound != nil {
-		r.NotFound.ServeHTTP(w, req)
+	r.count(&r.stats.notFound)
+	if notFound := r.notFoundHandler(hostPattern, path); notFound != nil {
+		notFound.ServeHTTP(w, req)
 	} else {
 		http.NotFound(w, req)
 	}
@@ -0,0 +1,23 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package clevergo
+
+import (
+	"io/fs"
+	"net/http"
+)
+
+// ServeFilesFS is a shortcut of Router.ServeFiles(path, http.FS(fsys)),
+// it is useful to serve files embedded by embed.FS:
+//     //go:embed static
+//     var static embed.FS
+//
+//     router.ServeFilesFS("/static/*filepath", static)
+func (r *Router) ServeFilesFS(path string, fsys fs.FS) {
+	r.ServeFiles(path, http.FS(fsys))
+}
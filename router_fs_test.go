@@ -0,0 +1,53 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package clevergo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRouterServeFilesFS(t *testing.T) {
+	router := NewRouter()
+	fsys := fstest.MapFS{
+		"hello.txt":     &fstest.MapFile{Data: []byte("hello")},
+		"dir/world.txt": &fstest.MapFile{Data: []byte("world")},
+	}
+
+	recv := catchPanic(func() {
+		router.ServeFilesFS("/noFilepath", fsys)
+	})
+	if recv == nil {
+		t.Fatal("registering path not ending with '*filepath' did not panic")
+	}
+
+	router.ServeFilesFS("/static/*filepath", fsys)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/static/hello.txt", http.StatusOK, "hello"},
+		{"/static/dir/world.txt", http.StatusOK, "world"},
+		{"/static/nope.txt", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected code %d, got %d", test.path, test.code, w.Code)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.path, test.body, w.Body)
+		}
+	}
+}
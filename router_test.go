@@ -5,12 +5,24 @@
 package clevergo
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type mockResponseWriter struct{}
@@ -45,6 +57,30 @@ func TestParams(t *testing.T) {
 	}
 }
 
+func TestParams_GetDefault(t *testing.T) {
+	ps := Params{
+		Param{"param1", "value1"},
+		Param{"param2", ""},
+	}
+	tests := []struct {
+		name     string
+		expected string
+		has      bool
+	}{
+		{"param1", "value1", true},
+		{"param2", "", true},
+		{"noKey", "default", false},
+	}
+	for _, test := range tests {
+		if val := ps.GetDefault(test.name, "default"); val != test.expected {
+			t.Errorf("Wrong value for %s: Got %q; Want %q", test.name, val, test.expected)
+		}
+		if has := ps.Has(test.name); has != test.has {
+			t.Errorf("Wrong existence for %s: Got %t; Want %t", test.name, has, test.has)
+		}
+	}
+}
+
 func TestParams_Int(t *testing.T) {
 	ps := Params{
 		Param{"param1", "-1"},
@@ -66,6 +102,31 @@ func TestParams_Int(t *testing.T) {
 	}
 }
 
+func TestParams_Int32(t *testing.T) {
+	ps := Params{
+		Param{"param1", "-1"},
+		Param{"param2", "0"},
+		Param{"param3", "2147483647"},
+		Param{"param4", "2147483648"},
+	}
+	tests := map[string]int32{
+		"param1": -1,
+		"param2": 0,
+		"param3": 2147483647,
+	}
+	for name, value := range tests {
+		if val, err := ps.Int32(name); err != nil || val != value {
+			t.Errorf("Wrong value for %s: Got %d; Want %d", name, val, value)
+		}
+	}
+	if val, err := ps.Int32("param4"); err == nil {
+		t.Errorf("Expected an error for out of range value; got %d", val)
+	}
+	if val, err := ps.Int32("noKey"); err == nil {
+		t.Errorf("Expected an error for not found key; got %d", val)
+	}
+}
+
 func TestParams_Int64(t *testing.T) {
 	ps := Params{
 		Param{"param1", "-1"},
@@ -87,6 +148,29 @@ func TestParams_Int64(t *testing.T) {
 	}
 }
 
+func TestParams_Uint(t *testing.T) {
+	ps := Params{
+		Param{"param1", "0"},
+		Param{"param2", "1"},
+		Param{"param3", "-1"},
+	}
+	tests := map[string]uint{
+		"param1": 0,
+		"param2": 1,
+	}
+	for name, value := range tests {
+		if val, err := ps.Uint(name); err != nil || val != value {
+			t.Errorf("Wrong value for %s: Got %d; Want %d", name, val, value)
+		}
+	}
+	if val, err := ps.Uint("param3"); err == nil {
+		t.Errorf("Expected an error for negative value; got %d", val)
+	}
+	if val, err := ps.Uint("noKey"); err == nil {
+		t.Errorf("Expected an error for not found key; got %d", val)
+	}
+}
+
 func TestParams_Uint64(t *testing.T) {
 	ps := Params{
 		Param{"param1", "0"},
@@ -166,6 +250,141 @@ func TestParams_Bool(t *testing.T) {
 	}
 }
 
+func TestParams_Duration(t *testing.T) {
+	ps := Params{
+		Param{"param1", "30s"},
+		Param{"param2", "5m"},
+		Param{"param3", "1h30m"},
+	}
+	tests := map[string]time.Duration{
+		"param1": 30 * time.Second,
+		"param2": 5 * time.Minute,
+		"param3": 90 * time.Minute,
+	}
+	for name, value := range tests {
+		if val, err := ps.Duration(name); err != nil || val != value {
+			t.Errorf("Wrong value for %s: Got %s; Want %s", name, val, value)
+		}
+	}
+	if val, err := ps.Duration("noKey"); err == nil {
+		t.Errorf("Expected an error for not found key; got %s", val)
+	}
+}
+
+func TestParams_Time(t *testing.T) {
+	ps := Params{
+		Param{"param1", "2020-01-02"},
+		Param{"param2", "2020-01-02T15:04:05Z"},
+		Param{"param3", "invalid"},
+	}
+	want := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	if val, err := ps.Time("param1", "2006-01-02"); err != nil || !val.Equal(want) {
+		t.Errorf("Wrong value for param1: Got %s; Want %s", val, want)
+	}
+	want = time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+	if val, err := ps.TimeRFC3339("param2"); err != nil || !val.Equal(want) {
+		t.Errorf("Wrong value for param2: Got %s; Want %s", val, want)
+	}
+	if val, err := ps.TimeRFC3339("param3"); err == nil {
+		t.Errorf("Expected an error for malformed value; got %s", val)
+	}
+	if val, err := ps.TimeRFC3339("noKey"); err == nil {
+		t.Errorf("Expected an error for not found key; got %s", val)
+	}
+}
+
+func TestParams_Slice(t *testing.T) {
+	ps := Params{
+		Param{"filepath", "/a/b/c"},
+		Param{"empty", ""},
+		Param{"root", "/"},
+		Param{"messy", "a//b/c/"},
+	}
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"filepath", []string{"a", "b", "c"}},
+		{"empty", []string{}},
+		{"root", []string{}},
+		{"messy", []string{"a", "b", "c"}},
+		{"noKey", []string{}},
+	}
+	for _, test := range tests {
+		if val := ps.Slice(test.name); !reflect.DeepEqual(val, test.expected) {
+			t.Errorf("Wrong value for %s: Got %q; Want %q", test.name, val, test.expected)
+		}
+	}
+
+	router := NewRouter()
+	router.UseRawPath = true
+	var elems []string
+	router.Get("/files/*path", func(w http.ResponseWriter, req *http.Request) {
+		elems = GetParams(req).Slice("path")
+	})
+	for _, unescape := range []bool{false, true} {
+		router.UnescapePathValues = unescape
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/files/a%2Fb/c", nil))
+		expected := []string{"a%2Fb", "c"}
+		if unescape {
+			expected = []string{"a", "b", "c"}
+		}
+		if !reflect.DeepEqual(elems, expected) {
+			t.Errorf("UnescapePathValues=%t: expected %q, got %q", unescape, expected, elems)
+		}
+	}
+}
+
+func TestParams_Map(t *testing.T) {
+	ps := Params{
+		Param{"name", "gopher"},
+		Param{"path", "/a/b"},
+		Param{"name", "duplicate"},
+	}
+	expected := map[string]string{"name": "gopher", "path": "/a/b"}
+	if m := ps.Map(); !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected map %v, got %v", expected, m)
+	}
+	if m := (Params{}).Map(); m != nil {
+		t.Errorf("expected nil map, got %v", m)
+	}
+}
+
+func TestParams_Each(t *testing.T) {
+	ps := Params{
+		Param{"id", "1"},
+		Param{"name", "gopher"},
+		Param{"path", "/a/b"},
+	}
+	var keys []string
+	err := ps.Each(func(key, value string) error {
+		keys = append(keys, key+"="+value)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := []string{"id=1", "name=gopher", "path=/a/b"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+
+	errStop := errors.New("stop")
+	keys = nil
+	err = ps.Each(func(key, value string) error {
+		keys = append(keys, key)
+		if key == "name" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("expected error %v, got %v", errStop, err)
+	}
+	if expected := []string{"id", "name"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+}
+
 func TestRouter(t *testing.T) {
 	router := NewRouter()
 
@@ -286,110 +505,1320 @@ func TestRouterAPI(t *testing.T) {
 	}
 }
 
-func TestRouterInvalidInput(t *testing.T) {
+func TestRouterShortcutsOptions(t *testing.T) {
+	handle := func(_ http.ResponseWriter, _ *http.Request) {}
+
 	router := NewRouter()
+	shortcuts := map[string]func(string, http.HandlerFunc, ...RouteOption){
+		http.MethodGet:     router.Get,
+		http.MethodHead:    router.Head,
+		http.MethodOptions: router.Options,
+		http.MethodPost:    router.Post,
+		http.MethodPut:     router.Put,
+		http.MethodPatch:   router.Patch,
+		http.MethodDelete:  router.Delete,
+	}
+	for method, shortcut := range shortcuts {
+		shortcut("/"+method, handle, RouteName(method))
+		url, err := router.URL(method)
+		if err != nil {
+			t.Errorf("route option of %s was not applied: %s", method, err)
+			continue
+		}
+		if url.String() != "/"+method {
+			t.Errorf("expected url %q, got %q", "/"+method, url)
+		}
+	}
+}
 
-	handle := func(_ http.ResponseWriter, _ *http.Request) {}
+func TestRouterAny(t *testing.T) {
+	router := NewRouter()
+	api := router.Group("/api")
+	router.Any("/any", echoHandler("any").ServeHTTP, RouteName("any"))
+	api.Any("/any", echoHandler("api any").ServeHTTP, RouteName("any"))
+
+	for _, method := range anyMethods {
+		for path, body := range map[string]string{"/any": "any", "/api/any": "api any"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, path, nil)
+			router.ServeHTTP(w, req)
+			if w.Body.String() != body {
+				t.Errorf("%s %s: expected body %q, got %q", method, path, body, w.Body)
+			}
+		}
+	}
 
-	recv := catchPanic(func() {
-		router.HandleFunc("", "/", handle)
-	})
-	if recv == nil {
-		t.Fatal("registering empty method did not panic")
+	for name, expected := range map[string]string{"any": "/any", "/api/any": "/api/any"} {
+		url, err := router.URL(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if url.String() != expected {
+			t.Errorf("expected url %q, got %q", expected, url)
+		}
 	}
+}
 
-	recv = catchPanic(func() {
-		router.Get("", handle)
-	})
-	if recv == nil {
-		t.Fatal("registering empty path did not panic")
+func TestRouterMatch(t *testing.T) {
+	router := NewRouter()
+	methods := []string{http.MethodGet, http.MethodPost}
+	router.Match(methods, "/form", echoHandler("form").ServeHTTP, RouteName("form"))
+	router.Group("/api").Match(methods, "/form", echoHandler("api form").ServeHTTP, RouteName("form"))
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/form", http.StatusOK, "form"},
+		{http.MethodPost, "/form", http.StatusOK, "form"},
+		{http.MethodPut, "/form", http.StatusMethodNotAllowed, "Method Not Allowed\n"},
+		{http.MethodGet, "/api/form", http.StatusOK, "api form"},
+		{http.MethodPost, "/api/form", http.StatusOK, "api form"},
+		{http.MethodDelete, "/api/form", http.StatusMethodNotAllowed, "Method Not Allowed\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("%s %s: unexpected response: Code=%d, Body=%q", test.method, test.path, w.Code, w.Body)
+		}
 	}
 
-	recv = catchPanic(func() {
-		router.Get("noSlashRoot", handle)
-	})
-	if recv == nil {
-		t.Fatal("registering path not beginning with '/' did not panic")
+	for name, expected := range map[string]string{"form": "/form", "/api/form": "/api/form"} {
+		url, err := router.URL(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if url.String() != expected {
+			t.Errorf("expected url %q, got %q", expected, url)
+		}
 	}
+}
 
-	recv = catchPanic(func() {
-		router.Get("/", nil)
+func TestRouterConstraint(t *testing.T) {
+	digits := regexp.MustCompile(`[0-9]+`)
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/users/:id", echoHandler("user"), RouteConstraint("id", digits))
+	router.Handle(http.MethodGet, "/users/:id/posts/:post", echoHandler("post"), RouteConstraint("post", digits))
+	router.Handle(http.MethodPost, "/users/:id", echoHandler("create"))
+	router.Handle(http.MethodGet, "/files/*filepath", echoHandler("file"), RouteConstraint("filepath", regexp.MustCompile(`/[a-z]+\.txt`)))
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/users/123", http.StatusOK, "user"},
+		{http.MethodGet, "/users/a123", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/users/123a", http.StatusMethodNotAllowed, ""},
+		{http.MethodPost, "/users/foo", http.StatusOK, "create"},
+		{http.MethodGet, "/users/foo/posts/1", http.StatusOK, "post"},
+		{http.MethodGet, "/users/foo/posts/bar", http.StatusNotFound, ""},
+		{http.MethodGet, "/files/readme.txt", http.StatusOK, "file"},
+		{http.MethodGet, "/files/readme.md", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s %s: expected code %d, got %d", test.method, test.path, test.code, w.Code)
+		}
+		if test.code == http.StatusOK && w.Body.String() != test.body {
+			t.Errorf("%s %s: expected body %q, got %q", test.method, test.path, test.body, w.Body)
+		}
+	}
+
+	// the constraint of GET route is respected while checking allowed methods.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/users/foo", nil)
+	router.ServeHTTP(w, req)
+	if allow := w.Header().Get("Allow"); allow != "OPTIONS, POST" {
+		t.Error("unexpected Allow header value: " + allow)
+	}
+
+	recv := catchPanic(func() {
+		router.Handle(http.MethodGet, "/posts/:id", echoHandler(""), RouteConstraint("name", digits))
 	})
 	if recv == nil {
-		t.Fatal("registering nil handler did not panic")
+		t.Error("registering constraint for unknown parameter did not panic")
 	}
 }
 
-func TestRouterChaining(t *testing.T) {
-	router1 := NewRouter()
-	router2 := NewRouter()
-	router1.NotFound = router2
-
-	fooHit := false
-	router1.Post("/foo", func(w http.ResponseWriter, req *http.Request) {
-		fooHit = true
-		w.WriteHeader(http.StatusOK)
+func TestRouterCompoundParams(t *testing.T) {
+	router := NewRouter()
+	router.HandleFunc(http.MethodGet, "/files/:name.:ext", func(w http.ResponseWriter, r *http.Request) {
+		ps := GetParams(r)
+		fmt.Fprintf(w, "%s %s", ps.Get("name"), ps.Get("ext"))
+	}, RouteName("file"))
+	router.HandleFunc(http.MethodGet, "/v:major.:minor/:page", func(w http.ResponseWriter, r *http.Request) {
+		ps := GetParams(r)
+		fmt.Fprintf(w, "%s %s %s", ps.Get("major"), ps.Get("minor"), ps.Get("page"))
 	})
-
-	barHit := false
-	router2.Post("/bar", func(w http.ResponseWriter, req *http.Request) {
-		barHit = true
-		w.WriteHeader(http.StatusOK)
+	router.HandleFunc(http.MethodGet, "/dates/:year-:month-:day", func(w http.ResponseWriter, r *http.Request) {
+		ps := GetParams(r)
+		fmt.Fprintf(w, "%s/%s/%s", ps.Get("year"), ps.Get("month"), ps.Get("day"))
 	})
 
-	r, _ := http.NewRequest(http.MethodPost, "/foo", nil)
-	w := httptest.NewRecorder()
-	router1.ServeHTTP(w, r)
-	if !(w.Code == http.StatusOK && fooHit) {
-		t.Errorf("Regular routing failed with router chaining.")
-		t.FailNow()
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/files/report.pdf", http.StatusOK, "report pdf"},
+		{"/files/my.report.pdf", http.StatusOK, "my.report pdf"},
+		{"/files/report", http.StatusNotFound, ""},
+		{"/files/.pdf", http.StatusNotFound, ""},
+		{"/files/report.", http.StatusNotFound, ""},
+		{"/v1.2/docs", http.StatusOK, "1 2 docs"},
+		{"/dates/2020-01-02", http.StatusOK, "2020/01/02"},
+		{"/dates/2020-01", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected code %d, got %d", test.path, test.code, w.Code)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.path, test.body, w.Body)
+		}
 	}
 
-	r, _ = http.NewRequest(http.MethodPost, "/bar", nil)
-	w = httptest.NewRecorder()
-	router1.ServeHTTP(w, r)
-	if !(w.Code == http.StatusOK && barHit) {
-		t.Errorf("Chained routing failed with router chaining.")
-		t.FailNow()
+	url, err := router.URL("file", "name", "report", "ext", "pdf")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if url.String() != "/files/report.pdf" {
+		t.Errorf("expected url %q, got %q", "/files/report.pdf", url)
 	}
 
-	r, _ = http.NewRequest(http.MethodPost, "/qax", nil)
-	w = httptest.NewRecorder()
-	router1.ServeHTTP(w, r)
-	if !(w.Code == http.StatusNotFound) {
-		t.Errorf("NotFound behavior failed with router chaining.")
-		t.FailNow()
+	for _, path := range []string{"/:a:b", "/:a.:b*c", "/:a.:"} {
+		recv := catchPanic(func() {
+			NewRouter().Get(path, func(http.ResponseWriter, *http.Request) {})
+		})
+		if recv == nil {
+			t.Errorf("registering invalid path %q did not panic", path)
+		}
 	}
-}
 
-func BenchmarkAllowed(b *testing.B) {
-	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+	// the compound segment conflicts with the routes in the same position,
+	// the messages name the compound pattern.
+	conflicts := []struct {
+		path string
+		err  string
+	}{
+		{"/files/:name", "a route is already registered for path '/files/:name' of existing route '/files/:name.:ext'"},
+		{"/files/:base-:size", "':base' in new path '/files/:base-:size' conflicts with existing wildcard ':name'"},
+		{"/dates/:year-:month-:day", "a route is already registered for path '/dates/:year-:month-:day'"},
+	}
+	for _, test := range conflicts {
+		err := router.TryHandle(http.MethodGet, test.path, echoHandler(""))
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%s: expected error %q, got %v", test.path, test.err, err)
+		}
+	}
+}
 
+func TestRouterOptionalParam(t *testing.T) {
 	router := NewRouter()
-	router.Post("/path", handlerFunc)
-	router.Get("/path", handlerFunc)
+	router.HandleFunc(http.MethodGet, "/files/:name?", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "file %q", GetParams(r).Get("name"))
+	}, RouteName("files"))
+	router.HandleFunc(http.MethodGet, "/users/:lang?", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "users %q", GetParams(r).Get("lang"))
+	}, RouteName("users"))
+
+	home := NewRouter()
+	home.HandleFunc(http.MethodGet, "/:lang?", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "home %q", GetParams(r).Get("lang"))
+	}, RouteName("home"))
 
-	b.Run("Global", func(b *testing.B) {
-		b.ReportAllocs()
-		for i := 0; i < b.N; i++ {
-			_ = router.allowed("*", http.MethodOptions)
+	tests := []struct {
+		router   *Router
+		path     string
+		code     int
+		body     string
+		location string
+	}{
+		{router, "/files", http.StatusOK, `file ""`, ""},
+		{router, "/files/report", http.StatusOK, `file "report"`, ""},
+		{router, "/files/", http.StatusMovedPermanently, "", "/files"},
+		{router, "/files/report/", http.StatusMovedPermanently, "", "/files/report"},
+		{router, "/users", http.StatusOK, `users ""`, ""},
+		{home, "/", http.StatusOK, `home ""`, ""},
+		{home, "/en", http.StatusOK, `home "en"`, ""},
+		{home, "/en/", http.StatusMovedPermanently, "", "/en"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		test.router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected code %d, got %d", test.path, test.code, w.Code)
 		}
-	})
-	b.Run("Path", func(b *testing.B) {
-		b.ReportAllocs()
-		for i := 0; i < b.N; i++ {
-			_ = router.allowed("/path", http.MethodOptions)
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.path, test.body, w.Body)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("%s: expected location %q, got %q", test.path, test.location, location)
+		}
+	}
+
+	urls := []struct {
+		router   *Router
+		name     string
+		args     []string
+		expected string
+	}{
+		{router, "files", nil, "/files"},
+		{router, "files", []string{"name", "report"}, "/files/report"},
+		{home, "home", nil, "/"},
+		{home, "home", []string{"lang", "en"}, "/en"},
+	}
+	for _, test := range urls {
+		url, err := test.router.URL(test.name, test.args...)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if url.String() != test.expected {
+			t.Errorf("expected url %q, got %q", test.expected, url)
 		}
+	}
+
+	recv := catchPanic(func() {
+		router.Get("/posts/id?", func(http.ResponseWriter, *http.Request) {})
+	})
+	if recv == nil {
+		t.Error("registering optional static segment did not panic")
+	}
+
+	recv = catchPanic(func() {
+		router.Get("/users/:id?/edit", func(http.ResponseWriter, *http.Request) {})
 	})
+	if recv == nil {
+		t.Error("registering non-trailing optional param did not panic")
+	}
+	if err := router.TryHandle(http.MethodGet, "/posts/:id?/comments/:comment", echoHandler("")); err == nil {
+		t.Error("expected an error for non-trailing optional param")
+	}
 }
 
-func TestRouterOPTIONS(t *testing.T) {
-	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+func TestRouterRoutes(t *testing.T) {
+	handle := func(_ http.ResponseWriter, _ *http.Request) {}
 
 	router := NewRouter()
-	router.Post("/path", handlerFunc)
+	if routes := router.Routes(); len(routes) != 0 {
+		t.Errorf("expected no routes, got %v", routes)
+	}
+
+	router.Post("/users", handle)
+	router.Get("/users/:id", handle, RouteName("user"))
+	router.Get("/users", handle)
+	router.Get("/files/:name?", handle)
+	router.Delete("/users/:id", handle)
+	router.Group("/api").Get("/", handle, RouteName("home"))
+
+	expected := []RouteInfo{
+		{Method: http.MethodDelete, Path: "/users/:id"},
+		{Method: http.MethodGet, Path: "/api/", Name: "/api/home"},
+		{Method: http.MethodGet, Path: "/files/:name?"},
+		{Method: http.MethodGet, Path: "/users"},
+		{Method: http.MethodGet, Path: "/users/:id", Name: "user"},
+		{Method: http.MethodPost, Path: "/users"},
+	}
+	routes := router.Routes()
+	if len(routes) != len(expected) {
+		t.Fatalf("expected routes %v, got %v", expected, routes)
+	}
+	for i, route := range routes {
+		if route.Route == nil || route.Route.path != route.Path {
+			t.Errorf("unexpected route of %v", route)
+		}
+		route.Route = nil
+		if route != expected[i] {
+			t.Errorf("expected route %v, got %v", expected[i], route)
+		}
+	}
+}
 
-	// test not allowed
+func TestRouterTryHandle(t *testing.T) {
+	router := NewRouter()
+	if err := router.TryHandle(http.MethodGet, "/users/:id", echoHandler("user"), RouteName("user")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		method  string
+		path    string
+		handler http.Handler
+		opts    []RouteOption
+		err     string
+	}{
+		{"", "/", echoHandler(""), nil, "method must not be empty"},
+		{http.MethodGet, "", echoHandler(""), nil, "path must begin with '/' in path ''"},
+		{http.MethodGet, "users", echoHandler(""), nil, "path must begin with '/' in path 'users'"},
+		{http.MethodGet, "/", nil, nil, "handler must not be nil"},
+		{http.MethodGet, "/about", echoHandler(""), []RouteOption{RouteName("user")}, "route name user is already registered"},
+		{http.MethodGet, "/users/:id", echoHandler(""), nil, "a route is already registered for path '/users/:id'"},
+		{http.MethodGet, "/users/:name", echoHandler(""), []RouteOption{RouteName("name")}, "':name' in new path '/users/:name' conflicts with existing wildcard ':id'"},
+		{http.MethodGet, "/posts", echoHandler(""), []RouteOption{RouteConstraint("id", regexp.MustCompile(`\d+`))}, "route constraint for unknown parameter 'id' in path '/posts'"},
+	}
+	for _, test := range tests {
+		err := router.TryHandle(test.method, test.path, test.handler, test.opts...)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+	}
+	if _, ok := router.NamedRoute("name"); ok {
+		t.Error("expected the name of the conflicting route not to be registered")
+	}
+
+	if err := router.TryHandle(http.MethodPatch, "/b/:", echoHandler("")); err == nil {
+		t.Error("expected an error for an empty parameter name")
+	}
+	if _, ok := router.trees[http.MethodPatch]; ok {
+		t.Error("expected no route tree for the rejected method")
+	}
+
+	// the optional parameter is registered both without and with the
+	// parameter, the latter conflicts with the existing wildcard.
+	if err := router.TryHandle(http.MethodGet, "/users/:name?", echoHandler("")); err == nil {
+		t.Error("expected an error for a conflicting optional parameter")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected the route not to be registered partially, got code %d", w.Code)
+	}
+
+	recv := catchPanic(func() {
+		router.Handle(http.MethodGet, "/", nil)
+	})
+	if recv != "handler must not be nil" {
+		t.Errorf("expected a panic, got %v", recv)
+	}
+}
+
+func TestRouterRegister(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/", echoHandler("home"), RouteName("home"))
+
+	err := router.Register([]RouteDef{
+		{Method: http.MethodGet, Path: "/users", Handler: echoHandler("users"), Name: "users"},
+		{Method: http.MethodGet, Path: "/users/:id", Handler: echoHandler("user"), Name: "user", Options: []RouteOption{
+			RouteConstraint("id", regexp.MustCompile(`\d+`)),
+		}},
+		{Method: http.MethodPost, Path: "/users", Handler: echoHandler("create")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, name := range []string{"users", "user"} {
+		if _, ok := router.NamedRoute(name); !ok {
+			t.Errorf("expected named route %q", name)
+		}
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/users", nil)
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "create" {
+		t.Errorf("expected body %q, got %q", "create", w.Body)
+	}
+
+	tests := []struct {
+		routes []RouteDef
+		err    string
+	}{
+		{
+			[]RouteDef{
+				{Method: http.MethodGet, Path: "/posts", Handler: echoHandler("")},
+				{Method: http.MethodGet, Path: "posts", Handler: echoHandler("")},
+			},
+			"route definition 1 (GET posts): path must begin with '/' in path 'posts'",
+		},
+		{
+			[]RouteDef{{Method: http.MethodGet, Path: "/about", Handler: echoHandler(""), Name: "home"}},
+			"route definition 0 (GET /about): route name home is already registered",
+		},
+		{
+			[]RouteDef{
+				{Method: http.MethodGet, Path: "/posts", Handler: echoHandler(""), Name: "posts"},
+				{Method: http.MethodGet, Path: "/articles", Handler: echoHandler(""), Name: "posts"},
+			},
+			"route definition 1 (GET /articles): route name posts is already registered",
+		},
+		{
+			[]RouteDef{{Method: http.MethodGet, Path: "/posts"}},
+			"route definition 0 (GET /posts): handler must not be nil",
+		},
+		{
+			[]RouteDef{{Path: "/posts", Handler: echoHandler("")}},
+			"route definition 0 ( /posts): method must not be empty",
+		},
+		{
+			[]RouteDef{
+				{Method: http.MethodGet, Path: "/posts", Handler: echoHandler("")},
+				{Method: http.MethodGet, Path: "/users/:name", Handler: echoHandler("")},
+			},
+			"route definition 1 (GET /users/:name): ",
+		},
+	}
+	for _, test := range tests {
+		err := router.Register(test.routes)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+	}
+	if router.HasRoute(http.MethodGet, "/posts") {
+		t.Error("expected no route to be registered on error")
+	}
+
+	// the options are applied once per route.
+	calls := 0
+	count := func(*Route) {
+		calls++
+	}
+	err = router.Register([]RouteDef{
+		{Method: http.MethodGet, Path: "/tags", Handler: echoHandler("tags"), Options: []RouteOption{count}},
+		{Method: http.MethodGet, Path: "/tags/:tag", Handler: echoHandler("tag"), Options: []RouteOption{count}},
+		{Method: http.MethodPost, Path: "/tags", Handler: echoHandler("create"), Options: []RouteOption{count}, Name: "tags"},
+		{Method: http.MethodGet, Path: "/dashboard", Handler: echoHandler("html"), Options: []RouteOption{RouteAccept("text/html")}},
+		{Method: http.MethodGet, Path: "/dashboard", Handler: echoHandler("json"), Options: []RouteOption{RouteAccept("application/json")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected the options to be applied 3 times, got %d", calls)
+	}
+	for _, path := range []string{"/tags", "/tags/:tag"} {
+		if !router.HasRoute(http.MethodGet, path) {
+			t.Errorf("expected route %q", path)
+		}
+	}
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	req.Header.Set("Accept", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "json" {
+		t.Errorf("expected the variant registered in the same batch, got %q", w.Body)
+	}
+}
+
+func TestRouterClone(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/users/:id", echoHandler("user"), RouteName("user"))
+	router.Host("api.example.com").Handle(http.MethodGet, "/ping", echoHandler("pong"))
+
+	clone := router.Clone()
+	clone.RedirectTrailingSlash = false
+	clone.Handle(http.MethodGet, "/posts", echoHandler("posts"), RouteName("posts"))
+	clone.Host("api.example.com").Handle(http.MethodGet, "/status", echoHandler("ok"))
+	router.Handle(http.MethodGet, "/about", echoHandler("about"))
+
+	tests := []struct {
+		router *Router
+		host   string
+		path   string
+		code   int
+		body   string
+	}{
+		{router, "", "/users/1", http.StatusOK, "user"},
+		{clone, "", "/users/1", http.StatusOK, "user"},
+		{router, "", "/users/1/", http.StatusMovedPermanently, ""},
+		{clone, "", "/users/1/", http.StatusNotFound, ""},
+		{router, "", "/posts", http.StatusNotFound, ""},
+		{clone, "", "/posts", http.StatusOK, "posts"},
+		{router, "", "/about", http.StatusOK, "about"},
+		{clone, "", "/about", http.StatusNotFound, ""},
+		{router, "api.example.com", "/ping", http.StatusOK, "pong"},
+		{clone, "api.example.com", "/ping", http.StatusOK, "pong"},
+		{router, "api.example.com", "/status", http.StatusNotFound, ""},
+		{clone, "api.example.com", "/status", http.StatusOK, "ok"},
+	}
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		req.Host = test.host
+		test.router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%d: expected code %d, got %d", i, test.code, w.Code)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%d: expected body %q, got %q", i, test.body, w.Body)
+		}
+	}
+
+	if _, ok := router.NamedRoute("posts"); ok {
+		t.Error("named routes of the clone should not affect the original router")
+	}
+	original, _ := router.NamedRoute("user")
+	cloned, _ := clone.NamedRoute("user")
+	if original != cloned {
+		t.Error("expected the routes to be shared")
+	}
+}
+
+func TestRouterRemoveRoute(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/users/:id", echoHandler("user"), RouteName("user"))
+	router.Handle(http.MethodPost, "/users/:id", echoHandler("update"))
+	router.Handle(http.MethodGet, "/files/:name?", echoHandler("file"))
+	router.Handle(http.MethodGet, "/*path", echoHandler("fallback"))
+
+	if router.RemoveRoute(http.MethodGet, "/users/1") || router.RemoveRoute(http.MethodGet, "/users/:name") ||
+		router.RemoveRoute(http.MethodDelete, "/users/:id") {
+		t.Error("removed a route which was not registered")
+	}
+	if !router.RemoveRoute(http.MethodGet, "/users/:id") {
+		t.Fatal("failed to remove route")
+	}
+	if router.RemoveRoute(http.MethodGet, "/users/:id") {
+		t.Error("removed a route twice")
+	}
+	if _, ok := router.NamedRoute("user"); ok {
+		t.Error("expected the route name to be released")
+	}
+	if !router.RemoveRoute(http.MethodGet, "/files/:name?") {
+		t.Fatal("failed to remove route")
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/users/1", "fallback"},
+		{http.MethodPost, "/users/1", "update"},
+		{http.MethodGet, "/files", "fallback"},
+		{http.MethodGet, "/files/report", "fallback"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Errorf("%s %s: expected body %q, got %q", test.method, test.path, test.body, w.Body)
+		}
+	}
+
+	if !router.RemoveRoute(http.MethodGet, "/*path") {
+		t.Fatal("failed to remove root catch-all route")
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected code %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	// registers the route again.
+	router.Handle(http.MethodGet, "/users/:id", echoHandler("user again"), RouteName("user"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "user again" {
+		t.Errorf("expected body %q, got %q", "user again", w.Body)
+	}
+	for _, route := range router.Routes() {
+		if route.Path == "/files/:name?" {
+			t.Errorf("unexpected removed route: %v", route)
+		}
+	}
+}
+
+func TestRouterRemoveRouteGlobalAllowed(t *testing.T) {
+	router := NewRouter()
+	router.Get("/users", echoHandler("users").ServeHTTP)
+	router.Post("/users", echoHandler("create").ServeHTTP)
+	router.Put("/users/:id", echoHandler("update").ServeHTTP)
+
+	allow := func() string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodOptions, "*", nil)
+		router.ServeHTTP(w, req)
+		return w.Header().Get("Allow")
+	}
+	if got := allow(); got != "GET, OPTIONS, POST, PUT" {
+		t.Errorf("unexpected Allow header %q", got)
+	}
+	router.RemoveRoute(http.MethodGet, "/users")
+	router.RemoveRoute(http.MethodPost, "/users")
+	if got := allow(); got != "OPTIONS, PUT" {
+		t.Errorf("expected removed methods not to be advertised, got %q", got)
+	}
+	router.RemoveRoute(http.MethodPut, "/users/:id")
+	if got := allow(); got != "" {
+		t.Errorf("expected no Allow header, got %q", got)
+	}
+}
+
+func TestRouterDumpRoutes(t *testing.T) {
+	handle := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := NewRouter()
+	var buf bytes.Buffer
+	if err := router.DumpRoutes(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := "METHOD  PATH  NAME\n"; buf.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, buf.String())
+	}
+	data, err := router.MarshalRoutesJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(data) != "[]" {
+		t.Errorf("expected JSON %q, got %q", "[]", data)
+	}
+
+	router.Get("/users/:id", handle, RouteName("user"))
+	router.Post("/users", handle)
+	router.Get("/", handle, RouteName("home"))
+	router.Host("api.example.com").Get("/ping", handle)
+
+	buf.Reset()
+	if err = router.DumpRoutes(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "METHOD  PATH                  NAME\n" +
+		"GET     /                     home\n" +
+		"GET     api.example.com/ping  \n" +
+		"GET     /users/:id            user\n" +
+		"POST    /users                \n"
+	if buf.String() != expected {
+		t.Errorf("expected output:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	data, err = router.MarshalRoutesJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedJSON := `[{"method":"GET","path":"/","name":"home"},` +
+		`{"method":"GET","host":"api.example.com","path":"/ping"},` +
+		`{"method":"GET","path":"/users/:id","name":"user"},` +
+		`{"method":"POST","path":"/users"}]`
+	if string(data) != expectedJSON {
+		t.Errorf("expected JSON %s, got %s", expectedJSON, data)
+	}
+}
+
+func TestRouteSetHandler(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/", echoHandler("hello"), RouteMiddleware(echoMiddleware("m1")))
+
+	for _, info := range router.Routes() {
+		handler := info.Route.Handler()
+		info.Route.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "decorated ")
+			handler.ServeHTTP(w, r)
+		}))
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "m1 decorated hello" {
+		t.Errorf("expected body %q, got %q", "m1 decorated hello", w.Body)
+	}
+
+	recv := catchPanic(func() {
+		router.Routes()[0].Route.SetHandler(nil)
+	})
+	if recv == nil {
+		t.Error("setting nil handler did not panic")
+	}
+}
+
+func TestRouterEscapedWildcard(t *testing.T) {
+	router := NewRouter()
+	router.SaveMatchedRoute = true
+	handle := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %v", GetRoute(req).Pattern(), GetParams(req))
+	}
+	router.Get(`/ratio/1\:1`, handle, RouteName("square"))
+	router.Get(`/ratio/16\:9/:size`, handle, RouteName("wide"))
+	router.Get(`/glob/\*.go`, handle)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/ratio/1:1", http.StatusOK, `/ratio/1\:1 []`},
+		{"/ratio/1:2", http.StatusNotFound, "404 page not found\n"},
+		{"/ratio/16:9/hd", http.StatusOK, `/ratio/16\:9/:size [{size hd}]`},
+		{"/glob/*.go", http.StatusOK, `/glob/\*.go []`},
+		{"/glob/main.go", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("%s: unexpected response: Code=%d, Body=%q", test.path, w.Code, w.Body)
+		}
+	}
+
+	if url := router.URLString("square"); url != "/ratio/1:1" {
+		t.Errorf("expected URL %q, got %q", "/ratio/1:1", url)
+	}
+	if url := router.URLString("wide", "size", "hd"); url != "/ratio/16:9/hd" {
+		t.Errorf("expected URL %q, got %q", "/ratio/16:9/hd", url)
+	}
+	if !router.HasRoute(http.MethodGet, `/ratio/1\:1`) {
+		t.Error("expected the escaped route exists")
+	}
+	if router.HasRoute(http.MethodGet, "/ratio/1:1") {
+		t.Error("expected no route with param")
+	}
+	if !router.RemoveRoute(http.MethodGet, `/ratio/1\:1`) {
+		t.Error("expected the escaped route is removed")
+	}
+}
+
+func TestRouterHasRoute(t *testing.T) {
+	handle := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := NewRouter()
+	router.Get("/", handle)
+	router.Get("/users/:id", handle, RouteConstraint("id", regexp.MustCompile(`[0-9]+`)))
+	router.Get("/files/*filepath", handle)
+	router.Post("/users", handle)
+
+	tests := []struct {
+		method   string
+		path     string
+		expected bool
+	}{
+		{http.MethodGet, "/", true},
+		{http.MethodGet, "/users/:id", true},
+		{http.MethodGet, "/users/:name", false},
+		{http.MethodGet, "/users/123", false},
+		{http.MethodGet, "/users", false},
+		{http.MethodGet, "/files/*filepath", true},
+		{http.MethodGet, "/files/foo", false},
+		{http.MethodPost, "/users", true},
+		{http.MethodPost, "/users/:id", false},
+		{http.MethodPut, "/users", false},
+	}
+	for _, test := range tests {
+		if has := router.HasRoute(test.method, test.path); has != test.expected {
+			t.Errorf("HasRoute(%q, %q) = %t, want %t", test.method, test.path, has, test.expected)
+		}
+	}
+}
+
+func TestRouterRootCatchAll(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/users", echoHandler("users"))
+	router.HandleFunc(http.MethodGet, "/*path", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "proxy %s", GetParams(r).Get("path"))
+	})
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/users", "users"},
+		{"/", "proxy /"},
+		{"/users/", "proxy /users/"},
+		{"/anything/deep", "proxy /anything/deep"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != test.body {
+			t.Errorf("%s: unexpected response: Code=%d, Body=%q", test.path, w.Code, w.Body)
+		}
+	}
+
+	if !router.HasRoute(http.MethodGet, "/*path") {
+		t.Error("expected root catch-all route exists")
+	}
+}
+
+func TestRouterMount(t *testing.T) {
+	sub := NewRouter()
+	sub.Use(echoMiddleware("sub"))
+	sub.HandleFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "user %s", GetParams(r).Get("id"))
+	}, RouteName("user"), RouteConstraint("id", regexp.MustCompile(`[0-9]+`)), RouteMeta("key", "value"), RouteTemplateName("user.html"))
+	sub.NotFound = echoHandler("sub not found")
+	admin := sub.Group("/admin", RouteGroupMiddleware(echoMiddleware("admin")))
+	admin.Handle(http.MethodPost, "/", echoHandler("admin"), RouteMiddleware(echoMiddleware("route")))
+
+	router := NewRouter()
+	router.Use(echoMiddleware("root"))
+	router.Mount("/api", sub)
+	sub.Handle(http.MethodGet, "/after", echoHandler("after"))
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/api/users/1", http.StatusOK, "root sub user 1"},
+		{http.MethodGet, "/api/users/foo", http.StatusNotFound, "404 page not found\n"},
+		{http.MethodPost, "/api/admin/", http.StatusOK, "root sub route admin admin"},
+		{http.MethodGet, "/api/after", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("%s %s: unexpected response: Code=%d, Body=%q", test.method, test.path, w.Code, w.Body)
+		}
+	}
+
+	url, err := router.URL("/api/user", "id", "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if url.String() != "/api/users/1" {
+		t.Errorf("expected url %q, got %q", "/api/users/1", url)
+	}
+
+	route, _ := router.NamedRoute("/api/user")
+	if value, _ := route.Meta("key"); value != "value" || route.TemplateName() != "user.html" {
+		t.Errorf("expected the route fields are copied, got meta %v, template name %q", value, route.TemplateName())
+	}
+	if route.Pattern() != "/api/users/:id" {
+		t.Errorf("expected pattern %q, got %q", "/api/users/:id", route.Pattern())
+	}
+}
+
+func TestRouterMountFallback(t *testing.T) {
+	sub := NewRouter()
+	sub.Get("/x", func(w http.ResponseWriter, req *http.Request) {
+		Pass(req)
+	}, RouteFallback(echoHandler("fallback")))
+
+	router := NewRouter()
+	router.Mount("/api", sub)
+	for _, r := range []*Router{sub, router} {
+		path := "/x"
+		if r == router {
+			path = "/api/x"
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != "fallback" {
+			t.Errorf("%s: unexpected response: Code=%d, Body=%q", path, w.Code, w.Body)
+		}
+	}
+}
+
+func TestRouterInvalidInput(t *testing.T) {
+	router := NewRouter()
+
+	handle := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	recv := catchPanic(func() {
+		router.HandleFunc("", "/", handle)
+	})
+	if recv == nil {
+		t.Fatal("registering empty method did not panic")
+	}
+
+	recv = catchPanic(func() {
+		router.Get("", handle)
+	})
+	if recv == nil {
+		t.Fatal("registering empty path did not panic")
+	}
+
+	recv = catchPanic(func() {
+		router.Get("noSlashRoot", handle)
+	})
+	if recv == nil {
+		t.Fatal("registering path not beginning with '/' did not panic")
+	}
+
+	recv = catchPanic(func() {
+		router.Get("/", nil)
+	})
+	if recv == nil {
+		t.Fatal("registering nil handler did not panic")
+	}
+}
+
+func TestRouterChaining(t *testing.T) {
+	router1 := NewRouter()
+	router2 := NewRouter()
+	router1.NotFound = router2
+
+	fooHit := false
+	router1.Post("/foo", func(w http.ResponseWriter, req *http.Request) {
+		fooHit = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	barHit := false
+	router2.Post("/bar", func(w http.ResponseWriter, req *http.Request) {
+		barHit = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r, _ := http.NewRequest(http.MethodPost, "/foo", nil)
+	w := httptest.NewRecorder()
+	router1.ServeHTTP(w, r)
+	if !(w.Code == http.StatusOK && fooHit) {
+		t.Errorf("Regular routing failed with router chaining.")
+		t.FailNow()
+	}
+
+	r, _ = http.NewRequest(http.MethodPost, "/bar", nil)
+	w = httptest.NewRecorder()
+	router1.ServeHTTP(w, r)
+	if !(w.Code == http.StatusOK && barHit) {
+		t.Errorf("Chained routing failed with router chaining.")
+		t.FailNow()
+	}
+
+	r, _ = http.NewRequest(http.MethodPost, "/qax", nil)
+	w = httptest.NewRecorder()
+	router1.ServeHTTP(w, r)
+	if !(w.Code == http.StatusNotFound) {
+		t.Errorf("NotFound behavior failed with router chaining.")
+		t.FailNow()
+	}
+}
+
+func TestRouterUse(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/before", echoHandler("before"))
+	router.Use(echoMiddleware("m1"), echoMiddleware("m2"))
+	router.Handle(http.MethodGet, "/after", echoHandler("after"))
+	router.Use(echoMiddleware("m3"))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/before", http.StatusOK, "m1 m2 m3 before"},
+		{"/after", http.StatusOK, "m1 m2 m3 after"},
+		{"/nope", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("expected code %d, got %d", test.code, w.Code)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("expected body %q, got %q", test.body, w.Body)
+		}
+	}
+}
+
+func TestRouterPanicHandler(t *testing.T) {
+	router := NewRouter()
+	panicHandled := false
+
+	router.PanicHandler = func(rw http.ResponseWriter, r *http.Request, p interface{}) {
+		panicHandled = true
+		if p != "oops!" {
+			t.Errorf("unexpected recovered value: %v", p)
+		}
+		rw.WriteHeader(http.StatusInternalServerError)
+	}
+
+	router.HandleFunc(http.MethodPut, "/user/:name", func(_ http.ResponseWriter, _ *http.Request) {
+		panic("oops!")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/user/gopher", nil)
+
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			t.Fatal("handling panic failed")
+		}
+	}()
+
+	router.ServeHTTP(w, req)
+
+	if !panicHandled {
+		t.Fatal("simulating failed")
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("unexpected response code %d want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	// panic propagates without PanicHandler.
+	router.PanicHandler = nil
+	recv := catchPanic(func() {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	})
+	if recv == nil {
+		t.Error("expected a panic, got nil")
+	}
+}
+
+func BenchmarkAllowed(b *testing.B) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := NewRouter()
+	router.Post("/path", handlerFunc)
+	router.Get("/path", handlerFunc)
+
+	b.Run("Global", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = router.allowed("*", http.MethodOptions)
+		}
+	})
+	b.Run("Path", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = router.allowed("/path", http.MethodOptions)
+		}
+	})
+}
+
+func TestRouterDisableParamsPool(t *testing.T) {
+	router := NewRouter()
+	router.DisableParamsPool = true
+	var params []Params
+	router.Get("/users/:id/posts/:post", func(w http.ResponseWriter, req *http.Request) {
+		params = append(params, GetParams(req))
+	})
+	for _, path := range []string{"/users/1/posts/2", "/users/3/posts/4"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	expected := []Params{
+		{{"id", "1"}, {"post", "2"}},
+		{{"id", "3"}, {"post", "4"}},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected params %v, got %v", expected, params)
+	}
+	if !router.Clone().DisableParamsPool {
+		t.Error("expected the clone to disable params pool")
+	}
+}
+
+func TestRouterGetValueUnmatchedParams(t *testing.T) {
+	router := NewRouter()
+	router.Get("/users/:id", echoHandler("user").ServeHTTP, RouteConstraint("id", regexp.MustCompile(`^\d+$`)))
+	router.Get("/files/:name.:ext", echoHandler("file").ServeHTTP)
+	for _, path := range []string{"/users/gopher", "/files/readme"} {
+		if route, ps, _ := router.getValue(router.trees[http.MethodGet], path); route != nil || ps != nil {
+			t.Errorf("%s: expected no route and params, got %v and %v", path, route, ps)
+		}
+	}
+}
+
+// BenchmarkParamsPool compares reusing the params by the pool with allocating
+// them per request, for routes with a few and many params.
+func BenchmarkParamsPool(b *testing.B) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+	paths := map[string][2]string{
+		"1Param":  {"/users/:id", "/users/1"},
+		"5Params": {"/:a/:b/:c/:d/:e", "/1/2/3/4/5"},
+	}
+	for _, name := range []string{"1Param", "5Params"} {
+		for _, disabled := range []bool{false, true} {
+			router := NewRouter()
+			router.DisableParamsPool = disabled
+			router.Get(paths[name][0], handlerFunc)
+			req := httptest.NewRequest(http.MethodGet, paths[name][1], nil)
+			mode := "Pool"
+			if disabled {
+				mode = "NoPool"
+			}
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				w := httptest.NewRecorder()
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					router.ServeHTTP(w, req)
+				}
+			})
+			b.Run(name+"/"+mode+"/Parallel", func(b *testing.B) {
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					w := httptest.NewRecorder()
+					for pb.Next() {
+						router.ServeHTTP(w, req)
+					}
+				})
+			})
+		}
+	}
+}
+
+func TestRouterAdvertiseHEAD(t *testing.T) {
+	router := NewRouter()
+	if !router.AdvertiseHEAD {
+		t.Fatal("expected AdvertiseHEAD is enabled by default")
+	}
+	router.Get("/get", echoHandler("get").ServeHTTP)
+	router.Handle(http.MethodHead, "/head", echoHandler("head"))
+	router.Get("/both", echoHandler("get").ServeHTTP)
+	router.Handle(http.MethodHead, "/both", echoHandler("head"))
+
+	tests := []struct {
+		handleHEAD    bool
+		advertiseHEAD bool
+		path          string
+		allow         string
+	}{
+		{false, true, "/get", "GET, OPTIONS"},
+		{false, true, "/head", "HEAD, OPTIONS"},
+		{false, true, "/both", "GET, HEAD, OPTIONS"},
+		{false, true, "*", "GET, HEAD, OPTIONS"},
+		{true, true, "/get", "GET, HEAD, OPTIONS"},
+		{true, true, "/both", "GET, HEAD, OPTIONS"},
+		{false, false, "/get", "GET, OPTIONS"},
+		{false, false, "/head", "OPTIONS"},
+		{false, false, "/both", "GET, OPTIONS"},
+		{false, false, "*", "GET, OPTIONS"},
+		{true, false, "/get", "GET, OPTIONS"},
+	}
+	for _, test := range tests {
+		router.HandleHEAD, router.AdvertiseHEAD = test.handleHEAD, test.advertiseHEAD
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, test.path, nil))
+		if allow := w.Header().Get("Allow"); allow != test.allow {
+			t.Errorf("HandleHEAD=%t AdvertiseHEAD=%t %s: expected Allow %q, got %q", test.handleHEAD, test.advertiseHEAD, test.path, test.allow, allow)
+		}
+	}
+
+	router.HandleHEAD, router.AdvertiseHEAD = true, false
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/head", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "OPTIONS" {
+		t.Errorf("unexpected response: Code=%d, Allow=%q", w.Code, w.Header().Get("Allow"))
+	}
+	if router.Clone().AdvertiseHEAD {
+		t.Error("expected the clone copies AdvertiseHEAD")
+	}
+}
+
+func TestRouterMethodNotAllowedBody(t *testing.T) {
+	router := NewRouter()
+	router.MethodNotAllowedBody = true
+	router.Get("/users", echoHandler("get").ServeHTTP)
+	router.Delete("/users", echoHandler("delete").ServeHTTP)
+	router.Handle(http.MethodHead, "/head", echoHandler("head"))
+	router.AdvertiseHEAD = false
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/users", "Method POST not allowed on /users; try DELETE, GET\n"},
+		{http.MethodPut, "/users", "Method PUT not allowed on /users; try DELETE, GET\n"},
+		{http.MethodPost, "/head", "Method POST not allowed on /head\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != http.StatusMethodNotAllowed || w.Body.String() != test.body {
+			t.Errorf("%s %s: unexpected response: Code=%d, Body=%q", test.method, test.path, w.Code, w.Body)
+		}
+	}
+
+	router.MethodNotAllowed = echoHandler("custom")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", nil))
+	if w.Body.String() != "custom" {
+		t.Errorf("expected the MethodNotAllowed handler takes priority, got %q", w.Body)
+	}
+	if !router.Clone().MethodNotAllowedBody {
+		t.Error("expected the clone copies MethodNotAllowedBody")
+	}
+}
+
+func TestRouterAllowHeaderFunc(t *testing.T) {
+	router := NewRouter()
+	router.AllowHeaderFunc = func(methods []string) string {
+		var allowed []string
+		for _, method := range methods {
+			if method != http.MethodHead {
+				allowed = append(allowed, method)
+			}
+		}
+		return strings.Join(allowed, ",")
+	}
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, GetAllowedMethods(req))
+	})
+	router.Get("/path", func(http.ResponseWriter, *http.Request) {})
+	router.Head("/path", func(http.ResponseWriter, *http.Request) {})
+
+	for _, method := range []string{http.MethodOptions, http.MethodPost} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/path", nil))
+		if allow := w.Header().Get("Allow"); allow != "GET,OPTIONS" {
+			t.Errorf("%s: expected Allow header %q, got %q", method, "GET,OPTIONS", allow)
+		}
+		if method == http.MethodPost && w.Body.String() != "[GET HEAD OPTIONS]" {
+			t.Errorf("expected allowed methods %q, got %q", "[GET HEAD OPTIONS]", w.Body)
+		}
+	}
+}
+
+func TestRouterOPTIONSBody(t *testing.T) {
+	router := NewRouter()
+	router.OPTIONSBody = true
+	router.Post("/path", func(http.ResponseWriter, *http.Request) {})
+	router.Get("/path", func(http.ResponseWriter, *http.Request) {})
+
+	tests := []struct {
+		accept string
+		ctype  string
+		body   string
+	}{
+		{"", "text/plain; charset=utf-8", "GET, OPTIONS, POST"},
+		{"text/html, application/json;q=0.9", "application/json; charset=utf-8", `["GET","OPTIONS","POST"]`},
+		{"application/json;q=0", "text/plain; charset=utf-8", "GET, OPTIONS, POST"},
+		{"application/json;q=0.1, text/plain", "text/plain; charset=utf-8", "GET, OPTIONS, POST"},
+		{"application/*", "application/json; charset=utf-8", `["GET","OPTIONS","POST"]`},
+		{"*/*", "text/plain; charset=utf-8", "GET, OPTIONS, POST"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodOptions, "/path", nil)
+		req.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if ctype := w.Header().Get("Content-Type"); ctype != test.ctype {
+			t.Errorf("%q: expected content type %q, got %q", test.accept, test.ctype, ctype)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("%q: expected body %q, got %q", test.accept, test.body, w.Body)
+		}
+	}
+
+	// GlobalOPTIONS takes precedence.
+	router.GlobalOPTIONS = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/path", nil))
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("expected empty %d response, got %d %q", http.StatusNoContent, w.Code, w.Body)
+	}
+}
+
+func TestRouterOPTIONS(t *testing.T) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := NewRouter()
+	router.Post("/path", handlerFunc)
+
+	// test not allowed
 	// * (server)
 	r, _ := http.NewRequest(http.MethodOptions, "*", nil)
 	w := httptest.NewRecorder()
@@ -479,6 +1908,46 @@ func TestRouterOPTIONS(t *testing.T) {
 	}
 }
 
+func TestRouterHandleHEAD(t *testing.T) {
+	router := NewRouter()
+	router.HandleFunc(http.MethodGet, "/get", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		fmt.Fprint(w, "get")
+	})
+	router.Handle(http.MethodGet, "/both", echoHandler("get"))
+	router.Handle(http.MethodHead, "/both", echoHandler("head"))
+
+	// disabled by default.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodHead, "/get", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected code %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	router.HandleHEAD = true
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("X-Method") != http.MethodHead {
+		t.Errorf("unexpected response: Code=%d, Header=%v, Body=%q", w.Code, w.Header(), w.Body)
+	}
+
+	// HEAD handler takes priority.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodHead, "/both", nil)
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "head" {
+		t.Errorf("expected body %q, got %q", "head", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodHead, "/nope", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
 func TestRouterNotAllowed(t *testing.T) {
 	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
 
@@ -528,6 +1997,124 @@ func TestRouterNotAllowed(t *testing.T) {
 	}
 }
 
+func TestRouterMatchingOrder(t *testing.T) {
+	// conflicting routes panic regardless of the registration order.
+	for _, paths := range [][]string{
+		{"/users/new", "/users/:id"},
+		{"/users/:id", "/users/new"},
+		{"/users/:id", "/users/:name"},
+		{"/files/*filepath", "/files/:name"},
+	} {
+		router := NewRouter()
+		recv := catchPanic(func() {
+			for _, path := range paths {
+				router.Handle(http.MethodGet, path, echoHandler(path))
+			}
+		})
+		if recv == nil {
+			t.Errorf("expected a panic for conflicting paths %v", paths)
+		}
+	}
+
+	router := NewRouter()
+	router.HandleHEAD = true
+	router.Handle(http.MethodGet, "/users/:id", echoHandler("get"))
+	router.Handle(http.MethodGet, "/*path", echoHandler("get catch-all"))
+	router.Handle(http.MethodHead, "/*path", echoHandler("head catch-all"))
+	router.Handle(MethodAny, "/users/:id", echoHandler("any"))
+	router.Handle(MethodAny, "/posts", echoHandler("any"))
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/users/1", "get"},
+		{http.MethodGet, "/posts", "get catch-all"},
+		{http.MethodHead, "/users/1", "head catch-all"},
+		{http.MethodPost, "/users/1", "any"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Errorf("%s %s: expected body %q, got %q", test.method, test.path, test.body, w.Body)
+		}
+	}
+}
+
+func TestRouterMethods(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodPut, "/users/:id", echoHandler(""))
+	router.Handle(http.MethodGet, "/users/:id", echoHandler(""))
+	router.Handle(http.MethodOptions, "/users/:id", echoHandler(""))
+	router.Handle(http.MethodPost, "/users", echoHandler(""))
+	router.Handle(MethodAny, "/users/:id", echoHandler(""))
+	router.Host("api.example.com").Handle(http.MethodDelete, "/users/:id", echoHandler(""))
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"/users/1", []string{http.MethodGet, http.MethodOptions, http.MethodPut}},
+		{"/users", []string{http.MethodPost}},
+		{"/users/", nil},
+		{"/nope", nil},
+	}
+	for _, test := range tests {
+		if methods := router.Methods(test.path); !reflect.DeepEqual(methods, test.expected) {
+			t.Errorf("%s: expected methods %v, got %v", test.path, test.expected, methods)
+		}
+	}
+}
+
+func TestRouterMethodAny(t *testing.T) {
+	router := NewRouter()
+	router.Handle("PROPFIND", "/dav/*filepath", echoHandler("propfind"))
+	router.Handle(MethodAny, "/dav/*filepath", echoHandler("any"))
+	router.Handle(http.MethodGet, "/path", echoHandler("get"))
+	router.Handle(MethodAny, "/path", echoHandler("any"))
+	router.Handle(http.MethodPost, "/other", echoHandler("post"))
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{"PROPFIND", "/dav/file", http.StatusOK, "propfind"},
+		{"MKCOL", "/dav/dir", http.StatusOK, "any"},
+		{http.MethodGet, "/dav/file", http.StatusOK, "any"},
+		{http.MethodGet, "/path", http.StatusOK, "get"},
+		{http.MethodDelete, "/path", http.StatusOK, "any"},
+		{http.MethodOptions, "/path", http.StatusOK, "any"},
+		{http.MethodGet, "/other", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/nope", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s %s: expected code %d, got %d", test.method, test.path, test.code, w.Code)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%s %s: expected body %q, got %q", test.method, test.path, test.body, w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/other", nil)
+	router.ServeHTTP(w, req)
+	if allow := w.Header().Get("Allow"); allow != "OPTIONS, POST" {
+		t.Errorf("unexpected Allow header value: %q", allow)
+	}
+	if allow := router.allowed("*", http.MethodOptions); strings.Contains(allow, MethodAny) {
+		t.Errorf("unexpected Allow header value: %q", allow)
+	}
+}
+
 func TestRouterNotFound(t *testing.T) {
 	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
 
@@ -560,36 +2147,573 @@ func TestRouterNotFound(t *testing.T) {
 		}
 	}
 
-	// Test custom not found handler
-	var notFound bool
-	router.NotFound = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-		rw.WriteHeader(http.StatusNotFound)
-		notFound = true
+	// Test custom not found handler
+	var notFound bool
+	router.NotFound = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+		notFound = true
+	})
+	r, _ := http.NewRequest(http.MethodGet, "/nope", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if !(w.Code == http.StatusNotFound && notFound == true) {
+		t.Errorf("Custom NotFound handler failed: Code=%d, Header=%v", w.Code, w.Header())
+	}
+
+	// Test other method than GET (want 308 instead of 301)
+	router.Patch("/path", handlerFunc)
+	r, _ = http.NewRequest(http.MethodPatch, "/path/", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if !(w.Code == http.StatusPermanentRedirect && fmt.Sprint(w.Header()) == "map[Location:[/path]]") {
+		t.Errorf("Custom NotFound handler failed: Code=%d, Header=%v", w.Code, w.Header())
+	}
+
+	// Test special case where no node for the prefix "/" exists
+	router = NewRouter()
+	router.Get("/a", handlerFunc)
+	r, _ = http.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if !(w.Code == http.StatusNotFound) {
+		t.Errorf("NotFound handling route / failed: Code=%d", w.Code)
+	}
+}
+
+func TestRouterNotFoundFunc(t *testing.T) {
+	router := NewRouter()
+	router.Post("/path", func(http.ResponseWriter, *http.Request) {})
+	router.NotFoundFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("custom not found"))
+	})
+	router.MethodNotAllowedFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("custom not allowed"))
+	})
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/nope", "custom not found"},
+		{"/path", "custom not allowed"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.path, test.body, w.Body)
+		}
+	}
+
+	router.NotFoundFunc(nil)
+	router.MethodNotAllowedFunc(nil)
+	if router.NotFound != nil || router.MethodNotAllowed != nil {
+		t.Error("expected nil handlers to restore the defaults")
+	}
+}
+
+func TestRouterStrictSlash(t *testing.T) {
+	router := NewRouter()
+	router.StrictSlash = true
+	router.Handle(http.MethodGet, "/users", echoHandler("users"))
+	router.Handle(http.MethodGet, "/posts/", echoHandler("posts"))
+
+	tests := []struct {
+		path     string
+		code     int
+		location string
+	}{
+		{"/users", http.StatusOK, ""},
+		{"/users/", http.StatusNotFound, ""},
+		{"/posts/", http.StatusOK, ""},
+		{"/posts", http.StatusNotFound, ""},
+		{"/USERS/", http.StatusNotFound, ""},
+		{"/USERS", http.StatusMovedPermanently, "/users"},
+		{"/../users", http.StatusMovedPermanently, "/users"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected code %d, got %d", test.path, test.code, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("%s: expected location %q, got %q", test.path, test.location, location)
+		}
+	}
+}
+
+func TestRouterRedirectCode(t *testing.T) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := NewRouter()
+	router.RedirectCode = http.StatusFound
+	router.Get("/path", handlerFunc)
+	router.Post("/path", handlerFunc)
+
+	tests := []struct {
+		method string
+		route  string
+	}{
+		{http.MethodGet, "/path/"}, // TSR -/
+		{http.MethodGet, "/PATH"},  // Fixed Case
+		{http.MethodPost, "/path/"},
+		{http.MethodPost, "/PATH"},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest(test.method, test.route, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "/path" {
+			t.Errorf("%s %s: unexpected redirection: Code=%d, Location=%q", test.method, test.route, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestRouterOnRedirect(t *testing.T) {
+	handlerFunc := func(_ http.ResponseWriter, _ *http.Request) {}
+
+	router := NewRouter()
+	router.Get("/path", handlerFunc)
+
+	var redirects []string
+	handled := false
+	router.OnRedirect = func(w http.ResponseWriter, req *http.Request, to string) bool {
+		redirects = append(redirects, to)
+		if handled {
+			w.WriteHeader(http.StatusTeapot)
+		}
+		return handled
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, "/path/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/path" {
+		t.Errorf("unexpected redirection: Code=%d, Location=%q", w.Code, w.Header().Get("Location"))
+	}
+
+	handled = true
+	r, _ = http.NewRequest(http.MethodGet, "/PATH?foo=bar", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusTeapot || w.Header().Get("Location") != "" {
+		t.Errorf("redirection was not suppressed: Code=%d, Location=%q", w.Code, w.Header().Get("Location"))
+	}
+
+	expected := []string{"/path", "/path?foo=bar"}
+	if !reflect.DeepEqual(redirects, expected) {
+		t.Errorf("expected redirects %v, got %v", expected, redirects)
+	}
+}
+
+func TestRouterCaseInsensitive(t *testing.T) {
+	router := NewRouter()
+	router.CaseInsensitive = true
+	router.HandleFunc(http.MethodGet, "/users/:name/Posts", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "posts of %s", GetParams(r).Get("name"))
+	})
+	router.HandleFunc(http.MethodGet, "/static/*filepath", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "file %s", GetParams(r).Get("filepath"))
+	})
+
+	tests := []struct {
+		path     string
+		code     int
+		body     string
+		location string
+	}{
+		{"/users/Gopher/Posts", http.StatusOK, "posts of Gopher", ""},
+		{"/USERS/Gopher/posts", http.StatusOK, "posts of Gopher", ""},
+		{"/Static/CSS/App.css", http.StatusOK, "file /CSS/App.css", ""},
+		{"/USERS/Gopher/posts/", http.StatusMovedPermanently, "", "/users/Gopher/Posts"},
+		{"/users/../USERS/Gopher/posts", http.StatusMovedPermanently, "", "/users/Gopher/Posts"},
+	}
+	for _, test := range tests {
+		r, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s: expected code %d, got %d", test.path, test.code, w.Code)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.path, test.body, w.Body)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("%s: expected location %q, got %q", test.path, test.location, location)
+		}
+	}
+
+	route, ps, _ := router.Lookup(http.MethodGet, "/USERS/Gopher/POSTS")
+	if route == nil || ps.Get("name") != "Gopher" {
+		t.Errorf("unexpected lookup result: %v, %v", route, ps)
+	}
+}
+
+func TestRouterCleanPathBeforeMatch(t *testing.T) {
+	router := NewRouter()
+	router.CleanPathBeforeMatch = true
+	router.HandleFunc(http.MethodPost, "/foo/bar", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path))
+	})
+
+	for _, path := range []string{"/foo/bar", "/foo//bar", "//foo/bar", "/foo/./bar", "/foo/baz/../bar"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost", nil)
+		req.URL.Path = path
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != "/foo/bar" {
+			t.Errorf("%s: unexpected response: Code=%d, Body=%q", path, w.Code, w.Body)
+		}
+	}
+
+	router.CleanPathBeforeMatch = false
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost", nil)
+	req.URL.Path = "/foo//bar"
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusPermanentRedirect {
+		t.Errorf("expected code %d, got %d", http.StatusPermanentRedirect, w.Code)
+	}
+}
+
+func TestRouterUseRawPath(t *testing.T) {
+	router := NewRouter()
+	router.UseRawPath = true
+	handle := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, GetParams(req).Get("name"))
+	}
+	router.HandleFunc(http.MethodGet, "/files/:name", handle)
+	router.HandleFunc(http.MethodGet, "/caf%C3%A9/:name", handle)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/files/a%2Fb", http.StatusOK, "a%2Fb"},
+		{"/files/a%20b", http.StatusOK, "a%20b"},
+		{"/files/a b", http.StatusOK, "a%20b"},
+		{"/caf%C3%A9/menu", http.StatusOK, "menu"},
+		{"/files/a%2Fb/", http.StatusMovedPermanently, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected status code %d, got %d", test.path, test.code, w.Code)
+		}
+		if test.code == http.StatusOK && w.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.path, test.body, w.Body)
+		}
+	}
+
+	// the escaped slash is kept by the trailing slash redirection.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/files/a%2Fb/", nil)
+	router.ServeHTTP(w, req)
+	if location := w.Header().Get("Location"); location != "/files/a%2Fb" {
+		t.Errorf("expected location %q, got %q", "/files/a%2Fb", location)
+	}
+
+	router.UnescapePathValues = true
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/files/a%20b", nil)
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "a b" {
+		t.Errorf("expected body %q, got %q", "a b", w.Body)
+	}
+}
+
+func TestRouterMethodOverride(t *testing.T) {
+	router := NewRouter()
+	router.MethodOverrideHeader = "X-HTTP-Method-Override"
+	router.MethodOverrideForm = "_method"
+	handle := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, req.Method)
+	}
+	router.HandleFunc(http.MethodPost, "/users/:id", handle)
+	router.HandleFunc(http.MethodPut, "/users/:id", handle)
+	router.HandleFunc(http.MethodDelete, "/users/:id", handle)
+	router.HandleFunc(http.MethodGet, "/users/:id", handle)
+
+	tests := []struct {
+		method string
+		header string
+		form   string
+		code   int
+		body   string
+	}{
+		{http.MethodPost, "", "", http.StatusOK, http.MethodPost},
+		{http.MethodPost, "put", "", http.StatusOK, http.MethodPut},
+		{http.MethodPost, "", "DELETE", http.StatusOK, http.MethodDelete},
+		{http.MethodPost, "PUT", "DELETE", http.StatusOK, http.MethodPut},
+		{http.MethodPost, "PATCH", "", http.StatusMethodNotAllowed, ""},
+		// only POST requests are overridden.
+		{http.MethodGet, "DELETE", "", http.StatusOK, http.MethodGet},
+	}
+	for _, test := range tests {
+		var body io.Reader
+		if test.form != "" {
+			body = strings.NewReader(url.Values{"_method": {test.form}}.Encode())
+		}
+		req := httptest.NewRequest(test.method, "/users/1", body)
+		if test.form != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if test.header != "" {
+			req.Header.Set("X-HTTP-Method-Override", test.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s %q %q: expected status code %d, got %d", test.method, test.header, test.form, test.code, w.Code)
+		}
+		if test.code == http.StatusOK && w.Body.String() != test.body {
+			t.Errorf("%s %q %q: expected body %q, got %q", test.method, test.header, test.form, test.body, w.Body)
+		}
+	}
+
+	// the body of other content types is not parsed.
+	req := httptest.NewRequest(http.MethodPost, "/users/1", strings.NewReader("_method=DELETE"))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != http.MethodPost || req.PostForm != nil {
+		t.Errorf("expected the body not to be parsed, got %q", w.Body)
+	}
+
+	// the body is limited by MaxBodySize before matching.
+	router.MaxBodySize = 16
+	req = httptest.NewRequest(http.MethodPost, "/users/1", strings.NewReader("_method=DELETE&name="+strings.Repeat("a", 32)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != http.MethodPost {
+		t.Errorf("expected the body exceeding the limit not to override the method, got %q", w.Body)
+	}
+}
+
+func TestRouterUnescapePathValues(t *testing.T) {
+	router := NewRouter()
+	router.UnescapePathValues = true
+	handle := func(w http.ResponseWriter, req *http.Request) {
+		ps := GetParams(req)
+		fmt.Fprintf(w, "%q %q", ps.Get("name"), ps.Get("filepath"))
+	}
+	router.HandleFunc(http.MethodGet, "/files/:name", handle, RouteName("file"))
+	router.HandleFunc(http.MethodGet, "/static/*filepath", handle, RouteName("static"))
+	router.HandleFunc(http.MethodGet, "/users/:name/", handle)
+	router.HandleFunc(http.MethodGet, "/café/:name", handle, RouteName("cafe"))
+
+	tests := []struct {
+		name string
+		args []string
+		url  string
+		body string
+	}{
+		{"file", []string{"name", "a/b"}, "/files/a%2Fb", `"a/b" ""`},
+		{"file", []string{"name", "a b"}, "/files/a%20b", `"a b" ""`},
+		{"file", []string{"name", "100%"}, "/files/100%25", `"100%" ""`},
+		{"file", []string{"name", "a%2Fb"}, "/files/a%252Fb", `"a%2Fb" ""`},
+		{"static", []string{"filepath", "a/b%2Fc.txt"}, "/static/a/b%252Fc.txt", `"" "/a/b%2Fc.txt"`},
+		{"static", []string{"filepath", "a b/c"}, "/static/a%20b/c", `"" "/a b/c"`},
+		{"cafe", []string{"name", "a"}, "/caf%C3%A9/a", `"a" ""`},
+		{"cafe", []string{"name", "a/b"}, "/caf%C3%A9/a%2Fb", `"a/b" ""`},
+		{"cafe", []string{"name", "é%"}, "/caf%C3%A9/%C3%A9%25", `"é%" ""`},
+	}
+	for _, test := range tests {
+		u, err := router.URL(test.name, test.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != test.url {
+			t.Errorf("%v: expected URL %q, got %q", test.args, test.url, u)
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+		router.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Errorf("%v: expected body %s, got %s", test.args, test.body, w.Body)
+		}
+	}
+
+	// the trailing slash redirection keeps the escaped slash.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/users/a%2Fb", nil)
+	router.ServeHTTP(w, req)
+	if location := w.Header().Get("Location"); location != "/users/a%2Fb/" {
+		t.Errorf("expected location %q, got %q", "/users/a%2Fb/", location)
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/users/caf%C3%A9%2F%25", nil)
+	router.ServeHTTP(w, req)
+	if location := w.Header().Get("Location"); location != "/users/caf%C3%A9%2F%25/" {
+		t.Errorf("expected location %q, got %q", "/users/caf%C3%A9%2F%25/", location)
+	}
+
+	router.UnescapePathValues = false
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/files/a%2Fb", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRouterMatchEmptyCatchAll(t *testing.T) {
+	router := NewRouter()
+	router.MatchEmptyCatchAll = true
+	router.HandleFunc(http.MethodGet, "/static/*filepath", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%q", GetParams(req).Get("filepath"))
+	})
+	router.HandleFunc(http.MethodGet, "/users/:id/files/*filepath", func(w http.ResponseWriter, req *http.Request) {
+		ps := GetParams(req)
+		fmt.Fprintf(w, "%s %q", ps.Get("id"), ps.Get("filepath"))
+	})
+	router.HandleFunc(http.MethodGet, "/posts/", func(w http.ResponseWriter, req *http.Request) {})
+
+	tests := []struct {
+		path     string
+		code     int
+		body     string
+		location string
+	}{
+		{"/static", http.StatusOK, `""`, ""},
+		{"/static/", http.StatusOK, `"/"`, ""},
+		{"/static/app.js", http.StatusOK, `"/app.js"`, ""},
+		{"/users/1/files", http.StatusOK, `1 ""`, ""},
+		{"/posts", http.StatusMovedPermanently, "", "/posts/"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected code %d, got %d", test.path, test.code, w.Code)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.path, test.body, w.Body)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("%s: expected location %q, got %q", test.path, test.location, location)
+		}
+	}
+
+	router.MatchEmptyCatchAll = false
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/static", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("expected code %d, got %d", http.StatusMovedPermanently, w.Code)
+	}
+}
+
+func TestRouterOnMatch(t *testing.T) {
+	var matched []string
+	router := NewRouter()
+	router.OnMatch = func(route *Route, req *http.Request) {
+		matched = append(matched, route.Pattern()+" "+GetParams(req).Get("id"))
+	}
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			matched = append(matched, "middleware")
+			next.ServeHTTP(w, req)
+		})
 	})
-	r, _ := http.NewRequest(http.MethodGet, "/nope", nil)
+	router.Handle(http.MethodGet, "/users/:id", echoHandler("user"))
+
+	for _, path := range []string{"/users/1", "/nope", "/users/2"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	expected := []string{"/users/:id 1", "middleware", "/users/:id 2", "middleware"}
+	if !reflect.DeepEqual(matched, expected) {
+		t.Errorf("expected %v, got %v", expected, matched)
+	}
+}
+
+func TestRouterShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	router := NewRouter()
+	router.Get("/slow", func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+	router.Get("/fast", func(w http.ResponseWriter, _ *http.Request) {})
+
+	slow := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "/slow", nil)
+		router.ServeHTTP(slow, req)
+		close(served)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := router.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected error %v, got %v", context.DeadlineExceeded, err)
+	}
+
 	w := httptest.NewRecorder()
-	router.ServeHTTP(w, r)
-	if !(w.Code == http.StatusNotFound && notFound == true) {
-		t.Errorf("Custom NotFound handler failed: Code=%d, Header=%v", w.Code, w.Header())
+	req, _ := http.NewRequest(http.MethodGet, "/fast", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected code %d, got %d", http.StatusServiceUnavailable, w.Code)
 	}
 
-	// Test other method than GET (want 308 instead of 301)
-	router.Patch("/path", handlerFunc)
-	r, _ = http.NewRequest(http.MethodPatch, "/path/", nil)
-	w = httptest.NewRecorder()
-	router.ServeHTTP(w, r)
-	if !(w.Code == http.StatusPermanentRedirect && fmt.Sprint(w.Header()) == "map[Location:[/path]]") {
-		t.Errorf("Custom NotFound handler failed: Code=%d, Header=%v", w.Code, w.Header())
+	close(release)
+	if err := router.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	<-served
+	if slow.Body.String() != "done" {
+		t.Errorf("expected the active request to finish, got %q", slow.Body)
 	}
+}
 
-	// Test special case where no node for the prefix "/" exists
-	router = NewRouter()
-	router.Get("/a", handlerFunc)
-	r, _ = http.NewRequest(http.MethodGet, "/", nil)
-	w = httptest.NewRecorder()
-	router.ServeHTTP(w, r)
-	if !(w.Code == http.StatusNotFound) {
-		t.Errorf("NotFound handling route / failed: Code=%d", w.Code)
+func TestRouterShutdownConcurrent(t *testing.T) {
+	var running, afterShutdown int32
+	var shutdown int32
+	router := NewRouter()
+	router.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.LoadInt32(&shutdown) == 1 {
+			atomic.AddInt32(&afterShutdown, 1)
+		}
+		atomic.AddInt32(&running, 1)
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := router.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	atomic.StoreInt32(&shutdown, 1)
+	if n := atomic.LoadInt32(&running); n != 0 {
+		t.Errorf("expected no running handler after shutdown, got %d", n)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&afterShutdown); n != 0 {
+		t.Errorf("expected no handler started after shutdown, got %d", n)
 	}
 }
 
@@ -659,6 +2783,58 @@ func TestRouterLookup(t *testing.T) {
 	}
 }
 
+func TestRouterLookupInto(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/users/:id/posts/:post", echoHandler(""))
+	router.Handle(http.MethodGet, "/about", echoHandler(""))
+
+	var ps Params
+	route, ok := router.LookupInto(http.MethodGet, "/users/1/posts/2", &ps)
+	if !ok || route.path != "/users/:id/posts/:post" {
+		t.Fatalf("unexpected route: %v, %t", route, ok)
+	}
+	wantParams := Params{Param{"id", "1"}, Param{"post", "2"}}
+	if !reflect.DeepEqual(ps, wantParams) {
+		t.Errorf("expected params %v, got %v", wantParams, ps)
+	}
+
+	route, ok = router.LookupInto(http.MethodGet, "/about", &ps)
+	if !ok || route.path != "/about" || len(ps) != 0 {
+		t.Errorf("unexpected route: %v, %t, %v", route, ok, ps)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		route, ok = router.LookupInto(method, "/nope", &ps)
+		if ok || route != nil || len(ps) != 0 {
+			t.Errorf("unexpected route: %v, %t, %v", route, ok, ps)
+		}
+	}
+
+	router.CaseInsensitive = true
+	route, ok = router.LookupInto(http.MethodGet, "/USERS/Foo/Posts/2", &ps)
+	wantParams = Params{Param{"id", "Foo"}, Param{"post", "2"}}
+	if !ok || !reflect.DeepEqual(ps, wantParams) {
+		t.Errorf("unexpected route: %v, %t, %v", route, ok, ps)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		router.LookupInto(http.MethodGet, "/users/1/posts/2", &ps)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkLookupInto(b *testing.B) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/users/:id/posts/:post", echoHandler(""))
+
+	ps := make(Params, 0, 2)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		router.LookupInto(http.MethodGet, "/users/1/posts/2", &ps)
+	}
+}
+
 func TestRouterParamsFromContext(t *testing.T) {
 	routed := false
 
@@ -704,6 +2880,32 @@ func TestRouterParamsFromContext(t *testing.T) {
 	}
 }
 
+func TestWithParams(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if ps := GetParams(req); ps != nil {
+		t.Errorf("expected nil params, got %v", ps)
+	}
+
+	want := Params{Param{"name", "gopher"}}
+	req = WithParams(req, want)
+	if ps := GetParams(req); !reflect.DeepEqual(ps, want) {
+		t.Errorf("Wrong parameter values: want %v, got %v", want, ps)
+	}
+}
+
+func TestWithRoute(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if route := GetRoute(req); route != nil {
+		t.Errorf("expected nil route, got %v", route)
+	}
+
+	want := newRoute("/users/:name", echoHandler(""))
+	req = WithRoute(req, want)
+	if route := GetRoute(req); route != want {
+		t.Errorf("Wrong route: want %v, got %v", want, route)
+	}
+}
+
 func TestRouterMatchedRoutePath(t *testing.T) {
 	route1 := "/user/:name"
 	routed1 := false
@@ -792,6 +2994,330 @@ func TestRouterServeFiles(t *testing.T) {
 	}
 }
 
+func TestRouterHandlePrefix(t *testing.T) {
+	router := NewRouter()
+	router.HandlePrefix("/debug/pprof/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", req.Method, req.URL.Path, req.URL.RawPath)
+	}))
+	router.Get("/debug/pprof/index", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "index")
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/debug/pprof/", http.StatusOK, "GET / "},
+		{http.MethodGet, "/debug/pprof/heap", http.StatusOK, "GET /heap "},
+		{http.MethodPost, "/debug/pprof/symbol", http.StatusOK, "POST /symbol "},
+		{http.MethodGet, "/debug/pprof/a%2Fb", http.StatusOK, "GET /a/b /a%2Fb"},
+		{http.MethodGet, "/debug/pprof/index", http.StatusOK, "index"},
+		{http.MethodGet, "/debug/pprof", http.StatusMovedPermanently, ""},
+		{http.MethodGet, "/debug", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s %s: expected status code %d, got %d", test.method, test.path, test.code, w.Code)
+		}
+		if test.code == http.StatusOK && w.Body.String() != test.body {
+			t.Errorf("%s %s: expected body %q, got %q", test.method, test.path, test.body, w.Body)
+		}
+	}
+
+	router.StrictSlash = true
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status code %d with StrictSlash, got %d", http.StatusNotFound, w.Code)
+	}
+
+	if recv := catchPanic(func() { router.HandlePrefix("noslash", echoHandler("")) }); recv == nil {
+		t.Error("registering prefix without leading slash did not panic")
+	}
+}
+
+func TestRouterHandleStd(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s%s", req.Method, req.Host, req.URL.Path)
+	})
+	router := NewRouter()
+	router.HandleStd("/", handler)
+	router.HandleStd("/api/", handler)
+	router.HandleStd("/favicon.ico", handler)
+	router.HandleStd("docs.example.org/docs/", handler)
+
+	tests := []struct {
+		method string
+		url    string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/", http.StatusOK, "GET example.com/"},
+		{http.MethodGet, "/foo/bar", http.StatusOK, "GET example.com/foo/bar"},
+		{http.MethodPost, "/api/users/1", http.StatusOK, "POST example.com/api/users/1"},
+		{http.MethodGet, "/api/", http.StatusOK, "GET example.com/api/"},
+		{http.MethodGet, "/api", http.StatusMovedPermanently, ""},
+		{http.MethodGet, "/favicon.ico", http.StatusOK, "GET example.com/favicon.ico"},
+		{http.MethodGet, "http://docs.example.org/docs/intro", http.StatusOK, "GET docs.example.org/docs/intro"},
+		{http.MethodGet, "http://docs.example.org/foo", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(test.method, test.url, nil))
+		if w.Code != test.code {
+			t.Errorf("%s %s: expected status code %d, got %d", test.method, test.url, test.code, w.Code)
+		}
+		if test.code == http.StatusOK && w.Body.String() != test.body {
+			t.Errorf("%s %s: expected body %q, got %q", test.method, test.url, test.body, w.Body)
+		}
+	}
+
+	if recv := catchPanic(func() { router.HandleStd("example.org", handler) }); recv == nil {
+		t.Error("registering pattern without path did not panic")
+	}
+}
+
+func TestRouterServeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clevergo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "favicon.ico")
+	if err = ioutil.WriteFile(filename, []byte("icon"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-time.Hour)
+	if err = os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	router := NewRouter()
+	router.NotFound = echoHandler("not found")
+	router.ServeFile("/favicon.ico", filename)
+	router.ServeFile("/missing.txt", filepath.Join(dir, "missing.txt"))
+
+	tests := []struct {
+		method          string
+		path            string
+		ifModifiedSince string
+		code            int
+		body            string
+	}{
+		{http.MethodGet, "/favicon.ico", "", http.StatusOK, "icon"},
+		{http.MethodHead, "/favicon.ico", "", http.StatusOK, ""},
+		{http.MethodGet, "/favicon.ico", time.Now().UTC().Format(http.TimeFormat), http.StatusNotModified, ""},
+		{http.MethodGet, "/missing.txt", "", http.StatusOK, "not found"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, nil)
+		if test.ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", test.ifModifiedSince)
+		}
+		router.ServeHTTP(w, req)
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("%s %s: expected %d %q, got %d %q", test.method, test.path, test.code, test.body, w.Code, w.Body)
+		}
+	}
+}
+
+func TestRouterServeFilesNotFound(t *testing.T) {
+	router := NewRouter()
+	router.ServeFiles("/static/*filepath", http.Dir("."))
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodGet, "/static/nope", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound || w.Body.String() != "404 page not found\n" {
+		t.Errorf("unexpected response: Code=%d, Body=%q", w.Code, w.Body)
+	}
+
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "custom not found: %s", r.URL.Path)
+	})
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/static/nope", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound || w.Body.String() != "custom not found: /static/nope" {
+		t.Errorf("unexpected response: Code=%d, Body=%q", w.Code, w.Body)
+	}
+	if h := w.Header().Get("X-Content-Type-Options"); h != "" {
+		t.Errorf("unexpected X-Content-Type-Options header: %q", h)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/static/LICENSE", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("serving existing file failed: Code=%d", w.Code)
+	}
+}
+
+func TestRouterServeFilesWithOptions(t *testing.T) {
+	var errs []error
+	router := NewRouter()
+	router.ServeFilesWithOptions("/static/*filepath", http.Dir("."), FileServeOptions{
+		CacheControl: "public, max-age=86400",
+		OnError: func(_ *http.Request, err error) {
+			errs = append(errs, err)
+		},
+	})
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodGet, "/static/LICENSE", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Errorf("serving existing file failed: Code=%d", w.Code)
+	}
+	if h := w.Header().Get("Cache-Control"); h != "public, max-age=86400" {
+		t.Errorf("unexpected Cache-Control header: %q", h)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/static/nope", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unexpected response: Code=%d", w.Code)
+	}
+	if h := w.Header().Get("Cache-Control"); h != "" {
+		t.Errorf("unexpected Cache-Control header: %q", h)
+	}
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "/static/LICENSE", nil)
+	router.ServeHTTP(w, r.WithContext(ctx))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if len(errs) != 1 || errs[0] != context.Canceled {
+		t.Errorf("expected error %v, got %v", context.Canceled, errs)
+	}
+}
+
+// countingFileSystem counts the opened files.
+type countingFileSystem struct {
+	http.FileSystem
+	opened int
+}
+
+func (fs *countingFileSystem) Open(name string) (http.File, error) {
+	fs.opened++
+	return fs.FileSystem.Open(name)
+}
+
+func TestRouterServeFilesContextDone(t *testing.T) {
+	fs := &countingFileSystem{FileSystem: http.Dir(".")}
+	router := NewRouter()
+	router.ServeFiles("/static/*filepath", fs)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	for _, ctx := range []context.Context{canceled, expired} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/static/LICENSE", nil).WithContext(ctx)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%v: expected status code %d, got %d", ctx.Err(), http.StatusServiceUnavailable, w.Code)
+		}
+	}
+	if fs.opened != 0 {
+		t.Errorf("expected no file is opened, got %d", fs.opened)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/LICENSE", nil))
+	if w.Code != http.StatusOK || fs.opened == 0 {
+		t.Errorf("unexpected response: Code=%d, opened=%d", w.Code, fs.opened)
+	}
+}
+
+func TestRouterServeFilesPrecompressed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clevergo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"app.js":     "plain js",
+		"app.js.br":  "brotli js",
+		"app.js.gz":  "gzip js",
+		"app.css":    "plain css",
+		"app.css.gz": "gzip css",
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := NewRouter()
+	router.ServeFilesWithOptions("/static/*filepath", http.Dir(dir), FileServeOptions{Precompressed: true})
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		rangeHeader    string
+		code           int
+		body           string
+		encoding       string
+		contentType    string
+	}{
+		{"/static/app.js", "gzip, deflate, br", "", http.StatusOK, "brotli js", "br", "javascript"},
+		{"/static/app.js", "gzip", "", http.StatusOK, "gzip js", "gzip", "javascript"},
+		{"/static/app.js", "br;q=0, gzip", "", http.StatusOK, "gzip js", "gzip", "javascript"},
+		{"/static/app.js", "", "", http.StatusOK, "plain js", "", "javascript"},
+		{"/static/app.css", "br, gzip", "", http.StatusOK, "gzip css", "gzip", "text/css"},
+		{"/static/app.css", "br", "", http.StatusOK, "plain css", "", "text/css"},
+		{"/static/app.js", "gzip", "bytes=0-3", http.StatusPartialContent, "gzip", "gzip", "javascript"},
+		{"/static/nope.js", "gzip", "", http.StatusNotFound, "", "", ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		if test.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		if test.rangeHeader != "" {
+			req.Header.Set("Range", test.rangeHeader)
+		}
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s %q: expected code %d, got %d", test.path, test.acceptEncoding, test.code, w.Code)
+			continue
+		}
+		if test.code == http.StatusNotFound {
+			continue
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("%s %q: expected body %q, got %q", test.path, test.acceptEncoding, test.body, w.Body)
+		}
+		if encoding := w.Header().Get("Content-Encoding"); encoding != test.encoding {
+			t.Errorf("%s %q: expected Content-Encoding %q, got %q", test.path, test.acceptEncoding, test.encoding, encoding)
+		}
+		if contentType := w.Header().Get("Content-Type"); !strings.Contains(contentType, test.contentType) {
+			t.Errorf("%s %q: expected Content-Type %q, got %q", test.path, test.acceptEncoding, test.contentType, contentType)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("%s %q: expected Vary header, got %q", test.path, test.acceptEncoding, vary)
+		}
+	}
+}
+
 func TestRouterNamedRoute(t *testing.T) {
 	tests := []struct {
 		path        string
@@ -827,6 +3353,105 @@ func TestRouterNamedRoute(t *testing.T) {
 	if recv == nil {
 		t.Error("expected a panic, got nil")
 	}
+
+	// invalid route names.
+	for _, name := range []string{"", "user name", "user\t", "\nuser"} {
+		recv = catchPanic(func() {
+			RouteName(name)
+		})
+		if recv == nil {
+			t.Errorf("expected a panic for invalid name %q, got nil", name)
+		}
+	}
+}
+
+func TestRouterNamedRoutes(t *testing.T) {
+	router := NewRouter()
+	if routes := router.NamedRoutes(); len(routes) != 0 {
+		t.Errorf("expected no named routes, got %v", routes)
+	}
+
+	router.Handle(http.MethodGet, "/", handlerStruct{}, RouteName("home"))
+	router.Handle(http.MethodGet, "/users/:id", handlerStruct{}, RouteName("user"))
+	router.Handle(http.MethodGet, "/about", handlerStruct{})
+
+	route, ok := router.NamedRoute("user")
+	if !ok || route.path != "/users/:id" {
+		t.Errorf("unexpected named route: %v, %t", route, ok)
+	}
+	if route, ok = router.NamedRoute("about"); ok || route != nil {
+		t.Errorf("unexpected named route: %v, %t", route, ok)
+	}
+
+	routes := router.NamedRoutes()
+	if len(routes) != 2 || routes["home"].path != "/" || routes["user"].path != "/users/:id" {
+		t.Errorf("unexpected named routes: %v", routes)
+	}
+	delete(routes, "home")
+	if _, ok = router.NamedRoute("home"); !ok {
+		t.Error("modifying the returned map should not affect the router")
+	}
+}
+
+func TestRouterURLQuery(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/search", handlerStruct{}, RouteName("search"))
+	router.Handle(http.MethodGet, "/users/:id/posts", handlerStruct{}, RouteName("posts"))
+
+	tests := []struct {
+		name     string
+		params   url.Values
+		args     []string
+		expected string
+	}{
+		{"search", url.Values{"q": {"go"}, "page": {"2"}}, nil, "/search?page=2&q=go"},
+		{"search", url.Values{"q": {"a b&c"}}, nil, "/search?q=a+b%26c"},
+		{"search", nil, nil, "/search"},
+		{"posts", url.Values{"sort": {"date"}}, []string{"id", "foo"}, "/users/foo/posts?sort=date"},
+	}
+	for _, test := range tests {
+		u, err := router.URLQuery(test.name, test.params, test.args...)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if u.String() != test.expected {
+			t.Errorf("expected url %q, got %q", test.expected, u)
+		}
+	}
+
+	if _, err := router.URLQuery("posts", url.Values{"sort": {"date"}}); err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
+
+func TestRouterMustURL(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/users/:id", handlerStruct{}, RouteName("user"))
+
+	if url := router.MustURL("user", "id", "foo"); url != "/users/foo" {
+		t.Errorf("expected url %q, got %q", "/users/foo", url)
+	}
+	if url := router.URLString("user", "id", "foo"); url != "/users/foo" {
+		t.Errorf("expected url %q, got %q", "/users/foo", url)
+	}
+
+	for _, args := range [][]string{nil, {"id"}} {
+		if url := router.URLString("user", args...); url != "" {
+			t.Errorf("expected an empty url for args %v, got %q", args, url)
+		}
+		recv := catchPanic(func() {
+			router.MustURL("user", args...)
+		})
+		if recv == nil {
+			t.Errorf("expected a panic for args %v, got nil", args)
+		}
+	}
+	if url := router.URLString("unregistered"); url != "" {
+		t.Errorf("expected an empty url, got %q", url)
+	}
+	if recv := catchPanic(func() { router.MustURL("unregistered") }); recv == nil {
+		t.Error("expected a panic, got nil")
+	}
 }
 
 func ExampleRouter_URL() {
@@ -868,6 +3493,27 @@ func ExampleRouter_URL() {
 	// /api/v2/users/bar
 }
 
+func ExampleRouter_Lookup() {
+	router := NewRouter()
+	router.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {})
+
+	// retrieves the matched route pattern in middleware, without turning on SaveMatchedRoute.
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route, _, _ := router.Lookup(r.Method, r.URL.Path); route != nil {
+				fmt.Printf("matched route: %s\n", route.Pattern())
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/foo", nil)
+	router.ServeHTTP(nil, req)
+
+	// Output:
+	// matched route: /users/:id
+}
+
 func ExampleGetParams() {
 	router := NewRouter()
 	router.Get("/post/:year/:month/:title", func(w http.ResponseWriter, r *http.Request) {
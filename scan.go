@@ -0,0 +1,104 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var errInvalidScanTarget = errors.New("scan target must be a non-nil pointer to struct")
+
+// Scan decodes params into the struct pointed to by dst, the fields are
+// mapped by the "param" tag, fields without the tag are ignored.
+// The supported field kinds are string, bool, integers and floats.
+// Missing params are left zero unless the "required" option is present:
+//  type Post struct {
+//      Year  int    `param:"year,required"`
+//      Month int    `param:"month"`
+//      Title string `param:"title"`
+//  }
+//
+//  var post Post
+//  err := ps.Scan(&post)
+func (ps Params) Scan(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errInvalidScanTarget
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("param")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		name, required := opts[0], false
+		for _, opt := range opts[1:] {
+			if opt != "required" {
+				return fmt.Errorf("unknown option %q of param tag on field %q", opt, field.Name)
+			}
+			required = true
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if !ps.Has(name) {
+			if required {
+				return fmt.Errorf("param %q is required", name)
+			}
+			continue
+		}
+		if err := setField(v.Field(i), ps.Get(name)); err != nil {
+			return fmt.Errorf("failed to scan param %q into field %q: %s", name, field.Name, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, value string) error {
+	if !field.CanSet() {
+		return errors.New("field is unexported")
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported kind %s", field.Kind())
+	}
+	return nil
+}
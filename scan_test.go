@@ -0,0 +1,114 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type scanTarget struct {
+	Name    string  `param:"name,required"`
+	Age     int     `param:"age"`
+	Level   int8    `param:"level"`
+	ID      uint64  `param:"id"`
+	Score   float64 `param:"score"`
+	Active  bool    `param:"active"`
+	Title   string  `param:""`
+	Ignored string
+	Skipped string `param:"-"`
+}
+
+func TestParamsScan(t *testing.T) {
+	ps := Params{
+		Param{"name", "foo"},
+		Param{"age", "18"},
+		Param{"level", "-3"},
+		Param{"id", "42"},
+		Param{"score", "9.5"},
+		Param{"active", "true"},
+		Param{"Title", "bar"},
+		Param{"Ignored", "ignored"},
+		Param{"-", "skipped"},
+	}
+	var dst scanTarget
+	if err := ps.Scan(&dst); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := scanTarget{Name: "foo", Age: 18, Level: -3, ID: 42, Score: 9.5, Active: true, Title: "bar"}
+	if !reflect.DeepEqual(dst, expected) {
+		t.Errorf("expected %+v, got %+v", expected, dst)
+	}
+
+	// missing optional params are left zero.
+	dst = scanTarget{}
+	if err := (Params{Param{"name", "foo"}}).Scan(&dst); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(dst, scanTarget{Name: "foo"}) {
+		t.Errorf("unexpected result: %+v", dst)
+	}
+}
+
+func TestParamsScanError(t *testing.T) {
+	var unknownOption struct {
+		Name string `param:"name,unknown"`
+	}
+	var unsupported struct {
+		Names []string `param:"name"`
+	}
+	var unexported struct {
+		name string `param:"name"`
+	}
+	tests := []struct {
+		ps  Params
+		dst interface{}
+	}{
+		{Params{}, nil},
+		{Params{}, scanTarget{}},
+		{Params{}, new(string)},
+		{Params{}, (*scanTarget)(nil)},
+		{Params{}, &scanTarget{}},
+		{Params{Param{"name", "foo"}, Param{"age", "foo"}}, &scanTarget{}},
+		{Params{Param{"name", "foo"}, Param{"level", "128"}}, &scanTarget{}},
+		{Params{Param{"name", "foo"}, Param{"id", "-1"}}, &scanTarget{}},
+		{Params{Param{"name", "foo"}, Param{"score", "foo"}}, &scanTarget{}},
+		{Params{Param{"name", "foo"}, Param{"active", "foo"}}, &scanTarget{}},
+		{Params{Param{"name", "foo"}}, &unknownOption},
+		{Params{Param{"name", "foo"}}, &unsupported},
+		{Params{Param{"name", "foo"}}, &unexported},
+	}
+	for _, test := range tests {
+		if err := test.ps.Scan(test.dst); err == nil {
+			t.Errorf("expected an error while scanning %v into %T", test.ps, test.dst)
+		}
+	}
+}
+
+func ExampleParams_Scan() {
+	type post struct {
+		Year  int    `param:"year,required"`
+		Month int    `param:"month,required"`
+		Title string `param:"title"`
+	}
+
+	router := NewRouter()
+	router.Get("/post/:year/:month/:title", func(w http.ResponseWriter, r *http.Request) {
+		var p post
+		if err := GetParams(r).Scan(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Printf("%s posted on %04d-%02d\n", p.Title, p.Year, p.Month)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/post/2020/01/foo", nil)
+	router.ServeHTTP(nil, req)
+
+	// Output:
+	// foo posted on 2020-01
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrFlushNotSupported is returned by SSE if the response writer does not
+// support flushing.
+var ErrFlushNotSupported = errors.New("the response writer does not support flushing")
+
+// ErrInvalidEventName is returned by SSEWriter.Send if the event name contains
+// a line break, which would terminate the event field.
+var ErrInvalidEventName = errors.New("the event name must not contain line breaks")
+
+// sseLineBreaks replaces the line breaks recognized by the event stream format,
+// that is CRLF, CR and LF, with LF.
+var sseLineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// SSEWriter writes server-sent events, see SSE.
+type SSEWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// SSE starts a server-sent events response, it sets the SSE headers and
+// writes the status code 200. ErrFlushNotSupported is returned if the writer,
+// or any writer wrapped by it, does not support flushing, the response is not
+// written in that case:
+//  sse, err := clevergo.SSE(w)
+//  if err != nil {
+//      http.Error(w, err.Error(), http.StatusInternalServerError)
+//      return
+//  }
+//  for msg := range messages {
+//      if err := sse.Send("message", msg); err != nil {
+//          return
+//      }
+//  }
+func SSE(w http.ResponseWriter) (*SSEWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrFlushNotSupported
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// disables the response buffering of proxies, such as nginx.
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &SSEWriter{w: w, flusher: flusher}, nil
+}
+
+// Send writes an event with the given name and data, and flushes it, the
+// event field is omitted if the name is empty, and the multi-line data is
+// split into multiple data fields at any of CRLF, CR and LF.
+// ErrInvalidEventName is returned if the name contains CR or LF, nothing is
+// written in that case.
+func (s *SSEWriter) Send(event, data string) error {
+	if strings.ContainsAny(event, "\r\n") {
+		return ErrInvalidEventName
+	}
+	var b bytes.Buffer
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(sseLineBreaks.Replace(data), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(s.w, b.String()); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// SendJSON is like Send, but the data is the JSON encoding of v.
+func (s *SSEWriter) SendJSON(event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.Send(event, string(data))
+}
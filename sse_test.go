@@ -0,0 +1,59 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+	router := NewRouter()
+	router.Use(Timeout(time.Minute))
+	router.Get("/events", func(w http.ResponseWriter, req *http.Request) {
+		sse, err := SSE(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sse.Send("", "hello")
+		sse.Send("update", "line 1\nline 2")
+		sse.Send("lines", "a\r\nb\rc\n")
+		if err := sse.Send("bad\r\ndata: injected", "x"); err != ErrInvalidEventName {
+			t.Errorf("expected error %v, got %v", ErrInvalidEventName, err)
+		}
+		sse.SendJSON("user", map[string]string{"name": "gopher"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if ctype := w.Header().Get("Content-Type"); ctype != "text/event-stream" {
+		t.Errorf("expected content type %q, got %q", "text/event-stream", ctype)
+	}
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	expected := "data: hello\n\nevent: update\ndata: line 1\ndata: line 2\n\nevent: lines\ndata: a\ndata: b\ndata: c\ndata: \n\nevent: user\ndata: {\"name\":\"gopher\"}\n\n"
+	if w.Body.String() != expected {
+		t.Errorf("expected body %q, got %q", expected, w.Body)
+	}
+}
+
+type nonFlusher struct {
+	http.ResponseWriter
+}
+
+func TestSSEFlushNotSupported(t *testing.T) {
+	tests := []http.ResponseWriter{
+		nonFlusher{httptest.NewRecorder()},
+		exposeInterfaces(newResponseWriter(nonFlusher{httptest.NewRecorder()})),
+	}
+	for _, w := range tests {
+		if _, err := SSE(w); err != ErrFlushNotSupported {
+			t.Errorf("%T: expected error %v, got %v", w, ErrFlushNotSupported, err)
+		}
+	}
+}
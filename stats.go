@@ -0,0 +1,57 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import "sync/atomic"
+
+// RouterStats is a snapshot of the request counters of a router, see
+// Router.EnableStats.
+type RouterStats struct {
+	// Requests is the number of all requests.
+	Requests uint64
+
+	// Matches is the number of requests served by the matched routes, the
+	// requests passed by all of the matched routes are excluded.
+	Matches uint64
+
+	// NotFound is the number of requests served by the NotFound handler.
+	NotFound uint64
+
+	// MethodNotAllowed is the number of requests replied with 405 Method Not
+	// Allowed.
+	MethodNotAllowed uint64
+
+	// Redirects is the number of requests redirected by RedirectTrailingSlash
+	// and RedirectFixedPath.
+	Redirects uint64
+}
+
+// routerStats holds the counters of RouterStats, which are updated atomically.
+type routerStats struct {
+	requests         uint64
+	matches          uint64
+	notFound         uint64
+	methodNotAllowed uint64
+	redirects        uint64
+}
+
+// Stats returns a snapshot of the request counters, the counters are zero
+// unless Router.EnableStats is turned on.
+func (r *Router) Stats() RouterStats {
+	return RouterStats{
+		Requests:         atomic.LoadUint64(&r.stats.requests),
+		Matches:          atomic.LoadUint64(&r.stats.matches),
+		NotFound:         atomic.LoadUint64(&r.stats.notFound),
+		MethodNotAllowed: atomic.LoadUint64(&r.stats.methodNotAllowed),
+		Redirects:        atomic.LoadUint64(&r.stats.redirects),
+	}
+}
+
+// count increments the given counter if Router.EnableStats is turned on.
+func (r *Router) count(counter *uint64) {
+	if r.EnableStats {
+		atomic.AddUint64(counter, 1)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterStats(t *testing.T) {
+	router := NewRouter()
+	router.EnableStats = true
+	router.HandleHEAD = true
+	router.Get("/users", echoHandler("users").ServeHTTP)
+	router.Get("/pass/:id", func(w http.ResponseWriter, req *http.Request) {
+		Pass(req)
+	})
+
+	requests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "/users", http.StatusOK},
+		{http.MethodHead, "/users", http.StatusOK},
+		{http.MethodGet, "/users/", http.StatusMovedPermanently},
+		{http.MethodGet, "/USERS", http.StatusMovedPermanently},
+		{http.MethodPost, "/users", http.StatusMethodNotAllowed},
+		{http.MethodOptions, "/users", http.StatusOK},
+		{http.MethodGet, "/pass/1", http.StatusNotFound},
+		{http.MethodGet, "/missing", http.StatusNotFound},
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(req.method, req.path, nil))
+		if w.Code != req.code {
+			t.Fatalf("%s %s: expected status code %d, got %d", req.method, req.path, req.code, w.Code)
+		}
+	}
+
+	expected := RouterStats{
+		Requests:         8,
+		Matches:          2,
+		NotFound:         2,
+		MethodNotAllowed: 1,
+		Redirects:        2,
+	}
+	if stats := router.Stats(); stats != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, stats)
+	}
+	if stats := router.Clone().Stats(); stats != (RouterStats{}) {
+		t.Errorf("expected the clone has its own counters, got %+v", stats)
+	}
+
+	router.EnableStats = false
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	if stats := router.Stats(); stats != expected {
+		t.Errorf("expected stats are not counted if disabled, got %+v", stats)
+	}
+}
+
+func TestRouterStatsConcurrent(t *testing.T) {
+	router := NewRouter()
+	router.EnableStats = true
+	router.Get("/", echoHandler("ok").ServeHTTP)
+
+	done := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		go func() {
+			for j := 0; j < 100; j++ {
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+	if stats := router.Stats(); stats.Requests != 1000 || stats.Matches != 1000 {
+		t.Errorf("expected 1000 requests and matches, got %+v", stats)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Timeout returns a middleware that cancels the request context after the
+// given duration, and writes a 503 Service Unavailable response only after
+// the handler returns, if it had not written anything by the deadline, so a
+// handler that ignores the context still holds the client until it returns.
+//
+// The handler runs in the calling goroutine and should observe
+// req.Context().Done() to give up in time. Once the deadline is exceeded, the
+// writes of a handler that has not written anything fail with
+// http.ErrHandlerTimeout, the responses already being written are left
+// untouched. Unlike http.TimeoutHandler, the response is not buffered, so
+// http.Flusher and http.Hijacker keep working.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, cancel := context.WithTimeout(req.Context(), d)
+			defer cancel()
+
+			tw := &timeoutResponseWriter{responseWriter: newResponseWriter(w), ctx: ctx}
+			next.ServeHTTP(exposeInterfaces(tw), req.WithContext(ctx))
+			if !tw.hijacked && tw.Status() == 0 && ctx.Err() == context.DeadlineExceeded {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+// timeoutResponseWriter rejects the writes of a response that was not
+// started before the deadline.
+type timeoutResponseWriter struct {
+	*responseWriter
+	ctx      context.Context
+	hijacked bool
+}
+
+func (w *timeoutResponseWriter) timedOut() bool {
+	return w.Status() == 0 && w.ctx.Err() == context.DeadlineExceeded
+}
+
+func (w *timeoutResponseWriter) WriteHeader(code int) {
+	if w.timedOut() {
+		return
+	}
+	w.responseWriter.WriteHeader(code)
+}
+
+func (w *timeoutResponseWriter) Write(p []byte) (int, error) {
+	if w.timedOut() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.responseWriter.Write(p)
+}
+
+func (w *timeoutResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.timedOut() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.responseWriter.ReadFrom(src)
+}
+
+func (w *timeoutResponseWriter) Flush() {
+	if w.timedOut() {
+		return
+	}
+	w.responseWriter.Flush()
+}
+
+func (w *timeoutResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.responseWriter.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
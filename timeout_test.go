@@ -0,0 +1,70 @@
+// Copyright 2020 CleverGo. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package clevergo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		code    int
+		body    string
+	}{
+		{
+			name: "fast",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte("hello"))
+			},
+			code: http.StatusOK, body: "hello",
+		},
+		{
+			name: "cooperative",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				<-req.Context().Done()
+			},
+			code: http.StatusServiceUnavailable, body: "Service Unavailable\n",
+		},
+		{
+			name: "late write",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				<-req.Context().Done()
+				if _, err := w.Write([]byte("late")); err != http.ErrHandlerTimeout {
+					t.Errorf("expected error %v, got %v", http.ErrHandlerTimeout, err)
+				}
+			},
+			code: http.StatusServiceUnavailable, body: "Service Unavailable\n",
+		},
+		{
+			name: "already written",
+			handler: func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte("partial"))
+				<-req.Context().Done()
+				w.Write([]byte(" rest"))
+			},
+			code: http.StatusOK, body: "partial rest",
+		},
+	}
+	for _, test := range tests {
+		router := NewRouter()
+		router.Use(Timeout(10 * time.Millisecond))
+		router.Get("/", test.handler)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%s: expected code %d, got %d", test.name, test.code, w.Code)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.name, test.body, w.Body)
+		}
+	}
+}
@@ -17,22 +17,54 @@ func min(a, b int) int {
 	return b
 }
 
-func longestCommonPrefix(a, b string) int {
-	i := 0
-	max := min(len(a), len(b))
-	for i < max && a[i] == b[i] {
-		i++
+// isEscapedWildcard reports whether the path has an escaped wildcard
+// character at i, such as "\\:" and "\\*", which is a literal character.
+func isEscapedWildcard(path string, i int) bool {
+	return path[i] == '\\' && i+1 < len(path) && (path[i+1] == ':' || path[i+1] == '*')
+}
+
+var wildcardUnescaper = strings.NewReplacer(`\:`, ":", `\*`, "*")
+
+// unescapeWildcards replaces the escaped wildcard characters of the path with
+// the literal characters.
+func unescapeWildcards(path string) string {
+	if strings.IndexByte(path, '\\') < 0 {
+		return path
+	}
+	return wildcardUnescaper.Replace(path)
+}
+
+// indexWildcard returns the index of the first c in s which is not escaped,
+// or -1 if there is none.
+func indexWildcard(s string, c byte) int {
+	for i := 0; i < len(s); i++ {
+		if isEscapedWildcard(s, i) {
+			i++
+		} else if s[i] == c {
+			return i
+		}
 	}
-	return i
+	return -1
+}
+
+// countWildcards counts the c in s which are not escaped.
+func countWildcards(s string, c byte) int {
+	return strings.Count(s, string(c)) - strings.Count(s, `\`+string(c))
 }
 
 // Search for a wildcard segment and check the name for invalid characters.
 // Returns -1 as index, if no wildcard was found.
 func findWildcard(path string) (wilcard string, i int, valid bool) {
 	// Find start
-	for start, c := range []byte(path) {
+	for start := 0; start < len(path); start++ {
+		// Escaped wildcard characters are literal characters
+		if isEscapedWildcard(path, start) {
+			start++
+			continue
+		}
+
 		// A wildcard starts with ':' (param) or '*' (catch-all)
-		if c != ':' && c != '*' {
+		if c := path[start]; c != ':' && c != '*' {
 			continue
 		}
 
@@ -52,14 +84,7 @@ func findWildcard(path string) (wilcard string, i int, valid bool) {
 }
 
 func countParams(path string) uint16 {
-	var n uint
-	for i := range []byte(path) {
-		switch path[i] {
-		case ':', '*':
-			n++
-		}
-	}
-	return uint16(n)
+	return uint16(countWildcards(path, ':') + countWildcards(path, '*'))
 }
 
 type nodeType uint8
@@ -79,6 +104,23 @@ type node struct {
 	priority  uint32
 	children  []*node
 	route     *Route
+
+	// catchAll is the root catch-all route, such as "/*path", which only
+	// exists on the root node, it matches any path that is not otherwise
+	// routed.
+	catchAll *Route
+}
+
+// clone returns a deep copy of the node and its children, the routes are shared.
+func (n *node) clone() *node {
+	c := *n
+	if n.children != nil {
+		c.children = make([]*node, len(n.children))
+		for i, child := range n.children {
+			c.children[i] = child.clone()
+		}
+	}
+	return &c
 }
 
 // Increments priority of the given child and reorders if necessary
@@ -108,6 +150,39 @@ func (n *node) incrementChildPrio(pos int) int {
 // addRoute adds a node with the given handle to the path.
 // Not concurrency-safe!
 func (n *node) addRoute(path string, route *Route) {
+	for _, segment := range strings.Split(path[:strings.LastIndexByte(path, '/')], "/") {
+		if strings.HasSuffix(segment, "?") && indexWildcard(segment, ':') >= 0 {
+			panic("only a trailing named parameter can be optional in path '" + path + "'")
+		}
+	}
+	path = compoundTreePath(path)
+
+	// An optional trailing parameter, such as "/files/:name?", is registered
+	// both without and with the parameter.
+	if path[len(path)-1] == '?' {
+		i := strings.LastIndexByte(path, '/')
+		if path[i+1] != ':' {
+			panic("only a trailing named parameter can be optional in path '" + path + "'")
+		}
+		prefix := path[:i]
+		if prefix == "" {
+			prefix = "/"
+		}
+		n.addRoute(prefix, route)
+		n.addRoute(path[:len(path)-1], route)
+		return
+	}
+
+	// The root catch-all has the lowest priority, it is stored separately
+	// and used as a fallback, so that it does not conflict with other routes.
+	if isRootCatchAll(path) {
+		if n.catchAll != nil {
+			panic("a root catch-all route is already registered for path '" + n.catchAll.path + "'")
+		}
+		n.catchAll = route
+		return
+	}
+
 	fullPath := path
 	n.priority++
 
@@ -122,13 +197,14 @@ walk:
 	for {
 		// Find the longest common prefix.
 		// This also implies that the common prefix contains no ':' or '*'
-		// since the existing key can't contain those chars.
-		i := longestCommonPrefix(path, n.path)
+		// since the existing key can't contain those chars, except the
+		// escaped ones, which are literal chars of the existing key.
+		i, j := n.commonPrefix(path)
 
 		// Split edge
-		if i < len(n.path) {
+		if j < len(n.path) {
 			child := node{
-				path:      n.path[i:],
+				path:      n.path[j:],
 				wildChild: n.wildChild,
 				nType:     static,
 				indices:   n.indices,
@@ -139,8 +215,8 @@ walk:
 
 			n.children = []*node{&child}
 			// []byte for proper unicode char conversion, see #65
-			n.indices = string([]byte{n.path[i]})
-			n.path = path[:i]
+			n.indices = string([]byte{n.path[j]})
+			n.path = n.path[:j]
 			n.route = nil
 			n.wildChild = false
 		}
@@ -168,14 +244,17 @@ walk:
 					}
 					prefix := fullPath[:strings.Index(fullPath, pathSeg)] + n.path
 					panic("'" + pathSeg +
-						"' in new path '" + fullPath +
+						"' in new path '" + route.treePathName(fullPath) +
 						"' conflicts with existing wildcard '" + n.path +
 						"' in existing prefix '" + prefix +
-						"'")
+						"'" + n.existingRoute())
 				}
 			}
 
-			idxc := path[0]
+			idxc, escaped := path[0], isEscapedWildcard(path, 0)
+			if escaped {
+				idxc = path[1]
+			}
 
 			// '/' after param
 			if n.nType == param && idxc == '/' && len(n.children) == 1 {
@@ -184,9 +263,11 @@ walk:
 				continue walk
 			}
 
-			// Check if a child with the next path byte exists
+			// Check if a child with the next path byte exists, a wildcard
+			// never matches the literal char of a child
+			literal := escaped || idxc != ':' && idxc != '*'
 			for i, c := range []byte(n.indices) {
-				if c == idxc {
+				if literal && c == idxc {
 					i = n.incrementChildPrio(i)
 					n = n.children[i]
 					continue walk
@@ -194,7 +275,7 @@ walk:
 			}
 
 			// Otherwise insert it
-			if idxc != ':' && idxc != '*' {
+			if literal {
 				// []byte for proper unicode char conversion, see #65
 				n.indices += string([]byte{idxc})
 				child := &node{}
@@ -208,13 +289,42 @@ walk:
 
 		// Otherwise add handle to current node
 		if n.route != nil {
-			panic("a route is already registered for path '" + fullPath + "'")
+			existing := ""
+			if n.route.path != fullPath {
+				existing = " of existing route '" + n.route.path + "'"
+			}
+			panic("a route is already registered for path '" + route.treePathName(fullPath) + "'" + existing)
 		}
 		n.route = route
 		return
 	}
 }
 
+// commonPrefix returns the length of the longest common prefix of the path and
+// the node path, in the path and in the node path respectively, which differ
+// if the path has escaped wildcards. The wildcards of the path only match the
+// param node, the escaped ones only match the literal chars of static nodes.
+func (n *node) commonPrefix(path string) (i, j int) {
+	for i < len(path) && j < len(n.path) {
+		c, width := path[i], 1
+		if isEscapedWildcard(path, i) {
+			c, width = path[i+1], 2
+		} else if (c == ':' || c == '*') && n.nType != param {
+			break
+		}
+		if c != n.path[j] {
+			break
+		}
+		i += width
+		j++
+	}
+	return
+}
+
+func isRootCatchAll(path string) bool {
+	return len(path) > 2 && path[:2] == "/*" && strings.IndexAny(path[2:], "/:*") < 0
+}
+
 func (n *node) insertChild(path, fullPath string, route *Route) {
 	for {
 		// Find prefix until first wildcard
@@ -238,13 +348,13 @@ func (n *node) insertChild(path, fullPath string, route *Route) {
 		// unreachable if we insert the wildcard here
 		if len(n.children) > 0 {
 			panic("wildcard segment '" + wildcard +
-				"' conflicts with existing children in path '" + fullPath + "'")
+				"' conflicts with existing children in path '" + fullPath + "'" + n.existingRoute())
 		}
 
 		if wildcard[0] == ':' { // param
 			if i > 0 {
 				// Insert prefix before the current wildcard
-				n.path = path[:i]
+				n.path = unescapeWildcards(path[:i])
 				path = path[i:]
 			}
 
@@ -288,7 +398,7 @@ func (n *node) insertChild(path, fullPath string, route *Route) {
 				panic("no / before catch-all in path '" + fullPath + "'")
 			}
 
-			n.path = path[:i]
+			n.path = unescapeWildcards(path[:i])
 
 			// First node: catchAll node with empty path
 			child := &node{
@@ -314,16 +424,162 @@ func (n *node) insertChild(path, fullPath string, route *Route) {
 	}
 
 	// If no wildcard was found, simply insert the path and handle
-	n.path = path
+	n.path = unescapeWildcards(path)
 	n.route = route
 }
 
+// walk calls fn for each route of the tree in depth-first order,
+// a route may be visited more than once if it was registered under multiple paths.
+func (n *node) walk(fn func(route *Route)) {
+	if n.route != nil {
+		fn(n.route)
+	}
+	if n.catchAll != nil {
+		fn(n.catchAll)
+	}
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+}
+
+// hasRoutes reports whether there is any route under the node.
+func (n *node) hasRoutes() bool {
+	if n.route != nil || n.catchAll != nil {
+		return true
+	}
+	for _, child := range n.children {
+		if child.hasRoutes() {
+			return true
+		}
+	}
+	return false
+}
+
+// existingRoute describes a route under the node for reporting conflicts, an
+// empty string is returned if there is no route.
+func (n *node) existingRoute() string {
+	path := ""
+	n.walk(func(route *Route) {
+		if path == "" {
+			path = route.path
+		}
+	})
+	if path == "" {
+		return ""
+	}
+	return " of existing route '" + path + "'"
+}
+
+// removeRoute clears the given route from the node and its children, the
+// nodes are kept.
+func (n *node) removeRoute(route *Route) {
+	if n.route == route {
+		n.route = nil
+	}
+	if n.catchAll == route {
+		n.catchAll = nil
+	}
+	for _, child := range n.children {
+		child.removeRoute(route)
+	}
+}
+
 // Returns the handle registered with the given path (key). The values of
 // wildcards are saved to a map.
 // If no handle can be found, a TSR (trailing slash redirect) recommendation is
 // made if a handle exists with an extra (without the) trailing slash for the
 // given path.
+// A route is treated as not found if its parameter constraints are unsatisfied.
+// The root catch-all route, if any, is returned if no other route matches.
+// The params may be returned along with a nil route, the caller is responsible
+// for releasing them.
 func (n *node) getValue(path string, params func() *Params) (route *Route, ps *Params, tsr bool) {
+	route, ps, tsr = n.find(path, params)
+	if route != nil && (len(route.segments) > 0 || len(route.constraints) > 0) {
+		values := ps
+		if params == nil {
+			// The parameter values are required for checking constraints.
+			_, values, _ = n.find(path, func() *Params {
+				ps := make(Params, 0, countParams(route.path))
+				return &ps
+			})
+		}
+		if values == nil || !route.expandParams(values) || !route.matchConstraints(*values) {
+			route, tsr = nil, false
+		}
+	}
+
+	if route == nil && n.catchAll != nil {
+		param, ok := n.catchAllParam(path)
+		if !ok {
+			return
+		}
+		route, tsr = n.catchAll, false
+		if params != nil {
+			if ps == nil {
+				ps = params()
+			}
+			// Drops the values saved by unmatched routes.
+			*ps = append((*ps)[:0], param)
+		}
+	}
+	return
+}
+
+// routeOf returns the route registered with exactly the given path, the path
+// is treated literally rather than a request path to be matched.
+func (n *node) routeOf(path string) *Route {
+	if n.catchAll != nil && n.catchAll.path == path {
+		return n.catchAll
+	}
+	if route, _, _ := n.find(unescapeWildcards(path), nil); route != nil && route.path == path {
+		return route
+	}
+	return nil
+}
+
+// catchAllParam returns the parameter of the root catch-all route for the
+// given path, it reports false if the route constraints are unsatisfied.
+func (n *node) catchAllParam(path string) (Param, bool) {
+	param := Param{Key: n.catchAll.path[2:], Value: path}
+	if len(n.catchAll.constraints) > 0 && !n.catchAll.matchConstraints(Params{param}) {
+		return param, false
+	}
+	return param, true
+}
+
+// treePathName returns the registered path of the route for the conflict
+// messages if the given tree path is rewritten from a compound segment.
+func (r *Route) treePathName(path string) string {
+	if len(r.segments) > 0 {
+		return r.path
+	}
+	return path
+}
+
+// compoundTreePath replaces each segment containing multiple named parameters,
+// such as ":name.:ext", with its first parameter, the captured value is split
+// by the route after matching.
+func compoundTreePath(path string) string {
+	if countWildcards(path, ':') < 2 {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if countWildcards(segment, ':') < 2 {
+			continue
+		}
+		start := indexWildcard(segment, ':')
+		end := start + 1
+		for end < len(segment) && isParamNameChar(segment[end]) {
+			end++
+		}
+		segments[i] = segment[:end]
+	}
+	return strings.Join(segments, "/")
+}
+
+func (n *node) find(path string, params func() *Params) (route *Route, ps *Params, tsr bool) {
 walk: // Outer loop for walking the tree
 	for {
 		prefix := n.path
@@ -226,6 +226,53 @@ func testRoutes(t *testing.T, routes []testRoute) {
 	//printChildren(tree, "")
 }
 
+func TestTreeEscapedWildcard(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		`/ratio/1\:1`,
+		`/ratio/1\:2/:name`,
+		`/ratio/16\:9`,
+		`/files/\*`,
+		`/files/\*/*filepath`,
+		`/time/:hour\::minute`,
+		`/\:id`,
+		`/\*name`,
+	}
+	for _, route := range routes {
+		tree.addRoute(route, newRoute(route, fakeHandler(route)))
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/ratio/1:1", false, `/ratio/1\:1`, nil},
+		{"/ratio/1:3", true, "", nil},
+		{"/ratio/11", true, "", nil},
+		{"/ratio/1:2/foo", false, `/ratio/1\:2/:name`, Params{Param{"name", "foo"}}},
+		{"/ratio/16:9", false, `/ratio/16\:9`, nil},
+		{"/files/*", false, `/files/\*`, nil},
+		{"/files/*/a/b", false, `/files/\*/*filepath`, Params{Param{"filepath", "/a/b"}}},
+		{"/files/a", true, "", nil},
+		{"/time/12:30", false, `/time/:hour\::minute`, Params{Param{"hour", "12"}, Param{"minute", "30"}}},
+		{"/:id", false, `/\:id`, nil},
+		{"/1", true, "", nil},
+		{"/*name", false, `/\*name`, nil},
+	})
+
+	checkPriorities(t, tree)
+}
+
+func TestTreeEscapedWildcardConflict(t *testing.T) {
+	routes := []testRoute{
+		newTestRoute(`/ratio/1\:1`, false),
+		newTestRoute(`/ratio/1\:1`, true),
+		newTestRoute(`/ratio/1:1`, true),
+		newTestRoute(`/ratio/:id`, true),
+		newTestRoute(`/user/:name`, false),
+		newTestRoute(`/user/\:name`, true),
+	}
+	testRoutes(t, routes)
+}
+
 func TestTreeWildcardConflict(t *testing.T) {
 	routes := []testRoute{
 		newTestRoute("/cmd/:tool/:sub", false),
@@ -258,7 +305,7 @@ func TestTreeChildConflict(t *testing.T) {
 		newTestRoute("/id/:id", false),
 		newTestRoute("/id:id", true),
 		newTestRoute("/:id", true),
-		newTestRoute("/*filepath", true),
+		newTestRoute("/*filepath", false),
 	}
 	testRoutes(t, routes)
 }
@@ -334,11 +381,46 @@ func TestTreeCatchAllConflict(t *testing.T) {
 func TestTreeCatchAllConflictRoot(t *testing.T) {
 	routes := []testRoute{
 		newTestRoute("/", false),
-		newTestRoute("/*filepath", true),
+		newTestRoute("/*filepath", false),
+		newTestRoute("/*path", true),
 	}
 	testRoutes(t, routes)
 }
 
+func TestTreeRootCatchAll(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/*path",
+		"/",
+		"/users",
+		"/users/:id",
+		"/users/:id/posts",
+		"/static/*filepath",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, newRoute(route, fakeHandler(route)))
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/", false, "/", nil},
+		{"/users", false, "/users", nil},
+		{"/users/", false, "/*path", Params{Param{"path", "/users/"}}},
+		{"/users/1", false, "/users/:id", Params{Param{"id", "1"}}},
+		{"/users/1/posts", false, "/users/:id/posts", Params{Param{"id", "1"}}},
+		{"/users/1/comments", false, "/*path", Params{Param{"path", "/users/1/comments"}}},
+		{"/static/app.js", false, "/static/*filepath", Params{Param{"filepath", "/app.js"}}},
+		{"/anything/deep", false, "/*path", Params{Param{"path", "/anything/deep"}}},
+	})
+
+	tree = &node{}
+	tree.addRoute("/*path", newRoute("/*path", fakeHandler("/*path")))
+	checkRequests(t, tree, testRequests{
+		{"/", false, "/*path", Params{Param{"path", "/"}}},
+		{"/anything/deep", false, "/*path", Params{Param{"path", "/anything/deep"}}},
+	})
+}
+
 func TestTreeCatchMaxParams(t *testing.T) {
 	tree := &node{}
 	var route = "/cmd/*filepath"
@@ -665,6 +747,18 @@ func TestTreeInvalidNodeType(t *testing.T) {
 	}
 }
 
+func TestTreeChildConflictExistingRoute(t *testing.T) {
+	tree := &node{}
+	tree.addRoute("/files/new", newRoute("/files/new", fakeHandler("/files/new")))
+	recv := catchPanic(func() {
+		tree.addRoute("/files/*filepath", newRoute("/files/*filepath", fakeHandler("/files/*filepath")))
+	})
+	expected := "wildcard segment '*filepath' conflicts with existing children in path '/files/*filepath' of existing route '/files/new'"
+	if recv != expected {
+		t.Errorf("expected panic %q, got %v", expected, recv)
+	}
+}
+
 func TestTreeWildcardConflictEx(t *testing.T) {
 	conflicts := [...]struct {
 		route        string
@@ -677,6 +771,7 @@ func TestTreeWildcardConflictEx(t *testing.T) {
 		{"/who/are/foo/bar", "/foo/bar", `/who/are/\*you`, `/\*you`},
 		{"/conxxx", "xxx", `/con:tact`, `:tact`},
 		{"/conooo/xxx", "ooo", `/con:tact`, `:tact`},
+		{"/con:name/xxx", ":name", `/con:tact`, `:tact`},
 	}
 
 	for _, conflict := range conflicts {
@@ -698,7 +793,7 @@ func TestTreeWildcardConflictEx(t *testing.T) {
 			tree.addRoute(conflict.route, newRoute(conflict.route, fakeHandler(conflict.route)))
 		})
 
-		if !regexp.MustCompile(fmt.Sprintf("'%s' in new path .* conflicts with existing wildcard '%s' in existing prefix '%s'", conflict.segPath, conflict.existSegPath, conflict.existPath)).MatchString(fmt.Sprint(recv)) {
+		if !regexp.MustCompile(fmt.Sprintf("'%s' in new path .* conflicts with existing wildcard '%s' in existing prefix '%s' of existing route '%s'$", conflict.segPath, conflict.existSegPath, conflict.existPath, conflict.existPath)).MatchString(fmt.Sprint(recv)) {
 			t.Fatalf("invalid wildcard conflict error (%v)", recv)
 		}
 	}